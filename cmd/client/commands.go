@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// errUsage is returned when a command is called with invalid arguments.
+var errUsage = errors.New("invalid usage")
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error
+}
+
+var commands = []command{
+	{"play", "start or resume playing", playerCommand("playing", func(ctx context.Context, c grpcapi.PlayerServiceClient) error {
+		_, err := c.Play(ctx, &grpcapi.PlayRequest{})
+		return err
+	})},
+	{"pause", "pause playing", playerCommand("paused", func(ctx context.Context, c grpcapi.PlayerServiceClient) error {
+		_, err := c.Pause(ctx, &grpcapi.PauseRequest{})
+		return err
+	})},
+	{"next", "play the next audio", playerCommand("skipped to the next audio", func(ctx context.Context, c grpcapi.PlayerServiceClient) error {
+		_, err := c.Next(ctx, &grpcapi.NextRequest{})
+		return err
+	})},
+	{"prev", "play the previous audio", playerCommand("skipped to the previous audio", func(ctx context.Context, c grpcapi.PlayerServiceClient) error {
+		_, err := c.Prev(ctx, &grpcapi.PrevRequest{})
+		return err
+	})},
+	{"seek", "continue the current audio from the position: seek POSITION, e.g. seek 1m30s", runSeek},
+	{"status", "show the player state and the current audio", runStatus},
+	{"sleep", "pause playing later or show the sleep timer: sleep [-after DURATION | -at TIME | -end | -tracks N | -cancel]", runSleep},
+	{"add", "add an audio to the playlist: add -name NAME -duration DURATION", runAdd},
+	{"get", "show an audio: get -id ID", runGet},
+	{"update", "change an audio: update -id ID [-name NAME] [-duration DURATION] [-expected-version N]", runUpdate},
+	{"rm", "delete an audio to the trash: rm -id ID [-expected-version N]", runRm},
+	{"ls", "list the playlist", runLs},
+	{"mv", "move an audio in the playlist: mv -id ID -index N", runMv},
+	{"trash", "list deleted audios kept in the trash", runTrash},
+	{"restore", "restore an audio from the trash: restore -id ID", runRestore},
+	{"purge", "remove audios from the trash permanently, all with -all: purge ID... | purge -all", runPurge},
+	{"undo", "undo your latest change of the playlist, anyone's with -global: undo [-global]", historyCommand("undo", "undone", func(ctx context.Context, c grpcapi.PlayerServiceClient, global bool) (*grpcapi.Change, error) {
+		resp, err := c.Undo(ctx, &grpcapi.UndoRequest{Global: global})
+		return resp.GetChange(), err
+	})},
+	{"redo", "redo your latest undone change of the playlist, anyone's with -global: redo [-global]", historyCommand("redo", "redone", func(ctx context.Context, c grpcapi.PlayerServiceClient, global bool) (*grpcapi.Change, error) {
+		resp, err := c.Redo(ctx, &grpcapi.RedoRequest{Global: global})
+		return resp.GetChange(), err
+	})},
+	{"tui", "interactive view of the playlist and the player", runTUI},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// parseArgs parses flags of a command, a single positional argument
+// before or after the flags is taken as the id if idFlag is not nil.
+func parseArgs(fs *flag.FlagSet, args []string, idFlag *string) error {
+	if idFlag != nil && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		*idFlag = args[0]
+		args = args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	rest := fs.Args()
+	if len(rest) == 1 && idFlag != nil && *idFlag == "" {
+		*idFlag = rest[0]
+		rest = nil
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, rest)
+	}
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func playerCommand(done string, call func(context.Context, grpcapi.PlayerServiceClient) error) func(context.Context, grpcapi.PlayerServiceClient, printer, []string) error {
+	return func(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+		}
+		if err := call(ctx, c); err != nil {
+			return err
+		}
+		st, err := c.GetStatus(ctx, &grpcapi.GetStatusRequest{})
+		if err != nil {
+			return err
+		}
+		if p.format == outputTable {
+			return p.print(st, messageTable(done))
+		}
+		return p.print(st, nil)
+	}
+}
+
+func runSeek(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: position is required", errUsage)
+	}
+	pos, err := time.ParseDuration(args[0])
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return playerCommand("seeked to "+pos.String(), func(ctx context.Context, c grpcapi.PlayerServiceClient) error {
+		_, err := c.Seek(ctx, &grpcapi.SeekRequest{Position: durationpb.New(pos)})
+		return err
+	})(ctx, c, p, nil)
+}
+
+func runStatus(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+	}
+	st, err := c.GetStatus(ctx, &grpcapi.GetStatusRequest{})
+	if err != nil {
+		return err
+	}
+	return p.print(st, statusTable(st))
+}
+
+func runSleep(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("sleep")
+	after := fs.Duration("after", 0, "pause playing after the duration")
+	at := fs.String("at", "", "pause playing at the time, RFC 3339 or HH:MM of the next occurrence")
+	end := fs.Bool("end", false, "pause playing at the end of the current audio")
+	tracks := fs.Int("tracks", 0, "pause playing after the number of audios, 1 is the current one")
+	cancel := fs.Bool("cancel", false, "cancel the sleep timer")
+	if err := parseArgs(fs, args, nil); err != nil {
+		return err
+	}
+	if fs.NFlag() > 1 {
+		return fmt.Errorf("%w: at most one flag is allowed", errUsage)
+	}
+
+	req := &grpcapi.SetSleepTimerRequest{}
+	switch {
+	case *cancel:
+		resp, err := c.CancelSleepTimer(ctx, &grpcapi.CancelSleepTimerRequest{})
+		if err != nil {
+			return err
+		}
+		return p.print(resp, messageTable("sleep timer canceled"))
+	case *after != 0:
+		req.Timer = &grpcapi.SetSleepTimerRequest_Duration{Duration: durationpb.New(*after)}
+	case *at != "":
+		t, err := parseClockTime(*at, time.Now())
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		req.Timer = &grpcapi.SetSleepTimerRequest_Time{Time: timestamppb.New(t)}
+	case *end:
+		req.Timer = &grpcapi.SetSleepTimerRequest_EndOfTrack{EndOfTrack: true}
+	case *tracks != 0:
+		req.Timer = &grpcapi.SetSleepTimerRequest_Tracks{Tracks: int32(*tracks)}
+	default:
+		resp, err := c.GetSleepTimer(ctx, &grpcapi.GetSleepTimerRequest{})
+		if err != nil {
+			return err
+		}
+		return p.print(resp, sleepTimerTable(resp.GetSleepTimer()))
+	}
+
+	resp, err := c.SetSleepTimer(ctx, req)
+	if err != nil {
+		return err
+	}
+	return p.print(resp, sleepTimerTable(resp.GetSleepTimer()))
+}
+
+// parseClockTime parses an RFC 3339 time or the next occurrence
+// of the local time of day in the HH:MM format after now.
+func parseClockTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	clock, err := time.ParseInLocation("15:04", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want RFC 3339 or HH:MM", s)
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func runAdd(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("add")
+	name := fs.String("name", "", "name of the audio")
+	duration := fs.Duration("duration", 0, "duration of the audio, e.g. 3m25s")
+	if err := parseArgs(fs, args, nil); err != nil {
+		return err
+	}
+	if *name == "" || *duration == 0 {
+		return fmt.Errorf("%w: -name and -duration are required", errUsage)
+	}
+
+	resp, err := c.CreateAudio(ctx, &grpcapi.CreateAudioRequest{
+		Audio: &grpcapi.Audio{
+			Name:     *name,
+			Duration: durationpb.New(*duration),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return p.print(resp.GetAudio(), audiosTable(resp.GetAudio()))
+}
+
+func runGet(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("get")
+	id := fs.String("id", "", "id of the audio")
+	if err := parseArgs(fs, args, id); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+
+	resp, err := c.ReadAudio(ctx, &grpcapi.ReadAudioRequest{Id: *id})
+	if err != nil {
+		return err
+	}
+	return p.print(resp.GetAudio(), audiosTable(resp.GetAudio()))
+}
+
+func runUpdate(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("update")
+	id := fs.String("id", "", "id of the audio")
+	name := fs.String("name", "", "new name of the audio (default: unchanged)")
+	duration := fs.Duration("duration", 0, "new duration of the audio (default: unchanged)")
+	expectedVersion := fs.Uint64("expected-version", 0, "version the audio must have to be changed (default: any)")
+	if err := parseArgs(fs, args, id); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+	if *name == "" && *duration == 0 {
+		return fmt.Errorf("%w: -name or -duration is required", errUsage)
+	}
+
+	audio := &grpcapi.Audio{Id: *id, Name: *name, Duration: durationpb.New(*duration)}
+	if *name == "" || *duration == 0 {
+		// the server replaces the whole audio, so unchanged fields are read first
+		cur, err := c.ReadAudio(ctx, &grpcapi.ReadAudioRequest{Id: *id})
+		if err != nil {
+			return err
+		}
+		if *name == "" {
+			audio.Name = cur.GetAudio().GetName()
+		}
+		if *duration == 0 {
+			audio.Duration = cur.GetAudio().GetDuration()
+		}
+		if *expectedVersion == 0 {
+			// fields read above must not be overwritten by a concurrent change
+			*expectedVersion = cur.GetAudio().GetVersion()
+		}
+	}
+
+	resp, err := c.UpdateAudio(ctx, &grpcapi.UpdateAudioRequest{Audio: audio, ExpectedVersion: *expectedVersion})
+	if err != nil {
+		return err
+	}
+	return p.print(resp.GetAudio(), audiosTable(resp.GetAudio()))
+}
+
+func runRm(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("rm")
+	id := fs.String("id", "", "id of the audio")
+	expectedVersion := fs.Uint64("expected-version", 0, "version the audio must have to be deleted (default: any)")
+	if err := parseArgs(fs, args, id); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+
+	resp, err := c.DeleteAudio(ctx, &grpcapi.DeleteAudioRequest{Id: *id, ExpectedVersion: *expectedVersion})
+	if err != nil {
+		return err
+	}
+	return p.print(resp, messageTable("deleted "+*id))
+}
+
+func runMv(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("mv")
+	id := fs.String("id", "", "id of the audio")
+	index := fs.Int("index", -1, "new position of the audio starting from zero, past the end means the end")
+	if err := parseArgs(fs, args, id); err != nil {
+		return err
+	}
+	if *id == "" || *index < 0 {
+		return fmt.Errorf("%w: -id and -index are required", errUsage)
+	}
+
+	resp, err := c.MoveAudio(ctx, &grpcapi.MoveAudioRequest{Id: *id, Index: int32(*index)})
+	if err != nil {
+		return err
+	}
+	return p.print(resp, messageTable(fmt.Sprintf("moved %s to %d", *id, *index)))
+}
+
+func runLs(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+	}
+	resp, err := c.ListAudio(ctx, &grpcapi.ListAudioRequest{})
+	if err != nil {
+		return err
+	}
+	return p.print(resp, audiosTable(resp.GetAudio()...))
+}
+
+func runTrash(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+	}
+	resp, err := c.ListTrash(ctx, &grpcapi.ListTrashRequest{})
+	if err != nil {
+		return err
+	}
+	return p.print(resp, trashTable(resp.GetAudios()...))
+}
+
+func runRestore(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("restore")
+	id := fs.String("id", "", "id of the deleted audio")
+	if err := parseArgs(fs, args, id); err != nil {
+		return err
+	}
+	if *id == "" {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+
+	resp, err := c.RestoreAudio(ctx, &grpcapi.RestoreAudioRequest{Id: *id})
+	if err != nil {
+		return err
+	}
+	return p.print(resp, messageTable(fmt.Sprintf("restored %s to %d", *id, resp.GetIndex())))
+}
+
+func runPurge(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+	fs := newFlagSet("purge")
+	all := fs.Bool("all", false, "purge the whole trash")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	ids := fs.Args()
+	for _, id := range ids {
+		if strings.HasPrefix(id, "-") {
+			return fmt.Errorf("%w: unexpected flag %s", errUsage, id)
+		}
+	}
+	// no ids purge the whole trash on the server, so it must be asked for explicitly
+	switch {
+	case *all && len(ids) > 0:
+		return fmt.Errorf("%w: ids can not be given with -all", errUsage)
+	case !*all && len(ids) == 0:
+		return fmt.Errorf("%w: no id is given, use -all to purge the whole trash", errUsage)
+	}
+	resp, err := c.PurgeTrash(ctx, &grpcapi.PurgeTrashRequest{Ids: ids})
+	if err != nil {
+		return err
+	}
+	return p.print(resp, messageTable(fmt.Sprintf("purged %d audios", resp.GetPurged())))
+}
+
+func historyCommand(name, done string, call func(context.Context, grpcapi.PlayerServiceClient, bool) (*grpcapi.Change, error)) func(context.Context, grpcapi.PlayerServiceClient, printer, []string) error {
+	return func(ctx context.Context, c grpcapi.PlayerServiceClient, p printer, args []string) error {
+		fs := newFlagSet(name)
+		global := fs.Bool("global", false, "change made by anyone rather than by you")
+		if err := parseArgs(fs, args, nil); err != nil {
+			return err
+		}
+
+		change, err := call(ctx, c, *global)
+		if err != nil {
+			return err
+		}
+		return p.print(change, changeTable(done, change))
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configEnv is the environment variable with the path of the config file.
+const configEnv = "PLAYER_CLIENT_CONFIG"
+
+// config holds the server address and credentials. Values are read from the
+// config file and overridden by the flags set on the command line:
+//
+//	addr: player.example.com:50052
+//	api_key: ...
+//	tls: true
+//	tls_ca: /etc/player/ca.pem
+//	timeout: 5s
+//	output: json
+type config struct {
+	Addr          string        `yaml:"addr"`
+	APIKey        string        `yaml:"api_key"`
+	Token         string        `yaml:"token"`
+	TLS           bool          `yaml:"tls"`
+	TLSCA         string        `yaml:"tls_ca"`
+	TLSCert       string        `yaml:"tls_cert"`
+	TLSKey        string        `yaml:"tls_key"`
+	TLSServerName string        `yaml:"tls_server_name"`
+	Timeout       time.Duration `yaml:"timeout"`
+	Output        string        `yaml:"output"`
+}
+
+func defaultConfig() config {
+	return config{
+		Addr:    "localhost:50052",
+		Timeout: 10 * time.Second,
+		Output:  outputTable,
+	}
+}
+
+// defaultConfigFile returns the config file from the environment
+// or, if it is not set, the file in the user config directory.
+func defaultConfigFile() string {
+	if f := os.Getenv(configEnv); f != "" {
+		return f
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gocloudcamp", "client.yaml")
+}
+
+// loadConfig reads the config file, a missing default file is not an error.
+func loadConfig(filename string, explicit bool) (config, error) {
+	cfg := defaultConfig()
+	if filename == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("read config file error: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse config file error: %w", err)
+	}
+	return cfg, nil
+}
+
+// bindFlags defines global flags storing values to cfg. Flags must be
+// parsed after the config file is loaded to override its values.
+func bindFlags(fs *flag.FlagSet, cfg *config) {
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "the address to connect to")
+	fs.StringVar(&cfg.APIKey, "api-key", cfg.APIKey, "API key to authenticate with")
+	fs.StringVar(&cfg.Token, "token", cfg.Token, "JWT bearer token to authenticate with")
+	fs.BoolVar(&cfg.TLS, "tls", cfg.TLS, "whether to connect with TLS")
+	fs.StringVar(&cfg.TLSCA, "tls-ca", cfg.TLSCA, "PEM file with CA bundle to verify the server certificate (empty value - system roots)")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "PEM file with client certificate for mutual TLS")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "PEM file with client private key for mutual TLS")
+	fs.StringVar(&cfg.TLSServerName, "tls-server-name", cfg.TLSServerName, "server name to verify the server certificate against")
+	fs.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "timeout of a call")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "output format: table, json, yaml")
+}
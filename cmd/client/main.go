@@ -2,83 +2,162 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
-	"sync"
+	"fmt"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/Karzoug/gocloudcamp/internal/grpcapi"
+	"github.com/Karzoug/gocloudcamp/internal/tlsconfig"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/Karzoug/gocloudcamp/pkg/playerclient"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/grpc/status"
 )
 
-var (
-	addr = flag.String("addr", "localhost:50052", "the address to connect to")
+const (
+	exitError = 1
+	exitUsage = 2
+	// exitStatusBase is added to the status code of a failed call to get the exit code,
+	// e.g. 15 for NotFound.
+	exitStatusBase = 10
 )
 
 func main() {
-	flag.Parse()
-	conn, err := grpc.Dial(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	configFile := fs.String("config", defaultConfigFile(), "YAML file with the server address and credentials, env "+configEnv)
+	fs.Usage = func() { usage(fs) }
+
+	// the config file is loaded before the other flags are parsed,
+	// so that the flags set on the command line override its values
+	explicit, filename := configFlag(args, *configFile)
+	cfg, err := loadConfig(filename, explicit)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	bindFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if !validOutput(cfg.Output) {
+		fmt.Fprintf(os.Stderr, "unknown output format %q\n", cfg.Output)
+		return exitUsage
+	}
+	cmd, ok := findCommand(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
 	}
-	defer conn.Close()
-	c := grpcapi.NewPlayerServiceClient(conn)
 
-	audios := []grpcapi.Audio{
-		{
-			Name:     "Simple ringtone",
-			Duration: durationpb.New(10 * time.Second),
-		},
-		{
-			Name:     "Meowing cat",
-			Duration: durationpb.New(18 * time.Second),
-		},
-		{
-			Name:     "Another Day in Paradise",
-			Duration: durationpb.New(123 * time.Second),
-		},
+	conn, err := dial(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
+	defer conn.Close()
 
-	var wg sync.WaitGroup
-	wg.Add(3)
-	for i := range audios {
-		go func(i int) {
-			defer wg.Done()
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-			r, err := c.CreateAudio(ctx, &grpcapi.CreateAudioRequest{Audio: &audios[i]})
-			if err != nil {
-				log.Fatalf("could not create audio: %v", err)
-			}
-			log.Printf("Audio added to playlist with id: %s", r.GetAudio().GetId())
-		}(i)
-	}
-	wg.Wait()
+	err = cmd.run(context.Background(), grpcapi.NewPlayerServiceClient(conn), printer{w: os.Stdout, format: cfg.Output}, fs.Args()[1:])
+	return exitCode(err)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	_, err = c.Play(ctx, &grpcapi.PlayRequest{})
-	if err != nil {
-		log.Fatalf("could not play audio: %v", err)
+// configFlag returns the value of the -config flag if it is set in args.
+func configFlag(args []string, def string) (bool, string) {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || arg == "--" {
+			break
+		}
+		if name != "config" {
+			continue
+		}
+		if hasValue {
+			return true, value
+		}
+		if i+1 < len(args) {
+			return true, args[i+1]
+		}
 	}
+	return false, def
+}
 
-	time.Sleep(5 * time.Second)
+func dial(cfg config) (*grpc.ClientConn, error) {
+	transportCreds := insecure.NewCredentials()
+	if cfg.TLS {
+		tlsCfg, err := tlsconfig.Client(cfg.TLSCA, cfg.TLSCert, cfg.TLSKey, cfg.TLSServerName)
+		if err != nil {
+			return nil, fmt.Errorf("create TLS config error: %w", err)
+		}
+		transportCreds = credentials.NewTLS(tlsCfg)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(transportCreds),
+		grpc.WithUnaryInterceptor(timeoutInterceptor(cfg.Timeout)),
+	}
+	if cfg.APIKey != "" || cfg.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(playerclient.NewCallCredentials(cfg.APIKey, cfg.Token, cfg.TLS)))
+	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	_, err = c.Pause(ctx, &grpcapi.PauseRequest{})
+	conn, err := grpc.Dial(cfg.Addr, opts...)
 	if err != nil {
-		log.Fatalf("could not play audio: %v", err)
+		return nil, fmt.Errorf("did not connect: %v", err)
 	}
+	return conn, nil
+}
 
-	time.Sleep(2 * time.Second)
+// timeoutInterceptor limits every unary call by the timeout,
+// streams are not limited as they may last as long as the client runs.
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
 
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-	_, err = c.Play(ctx, &grpcapi.PlayRequest{})
-	if err != nil {
-		log.Fatalf("could not play audio: %v", err)
+// exitCode prints the error and returns the exit code for it.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if errors.Is(err, errUsage) {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return exitUsage
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s\n", st.Code(), st.Message())
+	if st.Code() == codes.OK {
+		return exitError
+	}
+	return exitStatusBase + int(st.Code())
+}
+
+func usage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "Usage: client [flags] command [command flags]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-7s %s\n", c.name, c.usage)
 	}
+	fmt.Fprintln(out, "\nFlags:")
+	fs.PrintDefaults()
 }
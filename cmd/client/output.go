@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validOutput(format string) bool {
+	return format == outputTable || format == outputJSON || format == outputYAML
+}
+
+// printer writes responses in the chosen format.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// print writes the message as JSON or YAML,
+// in the table format it calls table instead.
+func (p printer) print(m proto.Message, table func(w io.Writer)) error {
+	switch p.format {
+	case outputJSON:
+		b, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.w, string(b))
+		return err
+	case outputYAML:
+		// YAML mirrors the JSON mapping of the message
+		b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(m)
+		if err != nil {
+			return err
+		}
+		var v any
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+func audiosTable(audios ...*grpcapi.Audio) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tDURATION\tVERSION")
+		for _, a := range audios {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", a.GetId(), a.GetName(), a.GetDuration().AsDuration(), a.GetVersion())
+		}
+	}
+}
+
+func trashTable(audios ...*grpcapi.DeletedAudio) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tDURATION\tINDEX\tDELETED\tEXPIRES")
+		for _, d := range audios {
+			a := d.GetAudio()
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", a.GetId(), a.GetName(), a.GetDuration().AsDuration(), d.GetIndex(),
+				d.GetDeleteTime().AsTime().Local().Format(time.DateTime), d.GetExpireTime().AsTime().Local().Format(time.DateTime))
+		}
+	}
+}
+
+func changeTable(done string, c *grpcapi.Change) func(w io.Writer) {
+	return func(w io.Writer) {
+		msg := fmt.Sprintf("%s: %s", done, c.GetOperation())
+		if a := c.GetAudio(); a != nil {
+			msg += fmt.Sprintf(" %s (%s)", a.GetId(), a.GetName())
+		}
+		if op := c.GetOperation(); op == "move" || op == "restore" {
+			msg += fmt.Sprintf(" to %d", c.GetIndex())
+		}
+		fmt.Fprintln(w, msg)
+	}
+}
+
+func statusTable(st *grpcapi.GetStatusResponse) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "STATE\tAUDIO ID\tNAME\tPOSITION\tDURATION")
+		a := st.GetAudio()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", st.GetState(), dash(a.GetId()), dash(a.GetName()),
+			st.GetPosition().AsDuration().Truncate(1e6), a.GetDuration().AsDuration())
+	}
+}
+
+func sleepTimerTable(t *grpcapi.SleepTimer) func(w io.Writer) {
+	return func(w io.Writer) {
+		switch {
+		case t == nil:
+			fmt.Fprintln(w, "no sleep timer")
+		case t.GetFireTime() != nil:
+			fmt.Fprintf(w, "pausing at %s\n", t.GetFireTime().AsTime().Local().Format(time.DateTime))
+		case t.GetTracks() == 1:
+			fmt.Fprintln(w, "pausing at the end of the current audio")
+		default:
+			fmt.Fprintf(w, "pausing after %d audios\n", t.GetTracks())
+		}
+	}
+}
+
+func messageTable(msg string) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, msg)
+	}
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// tickInterval is the interval the progress bar is redrawn at.
+	tickInterval = 200 * time.Millisecond
+	// statusPollInterval is the interval the status is reloaded at to catch up
+	// with changes not delivered as events, e.g. seeks, or the server is checked at while offline.
+	statusPollInterval = 5 * time.Second
+	seekStep           = 10 * time.Second
+
+	watchHeartbeat    = 10 * time.Second
+	minReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay = 10 * time.Second
+)
+
+var (
+	titleStyle   = lipgloss.NewStyle().Bold(true)
+	currentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	cursorStyle  = lipgloss.NewStyle().Reverse(true)
+	dimStyle     = lipgloss.NewStyle().Faint(true)
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+type tuiMode uint8
+
+const (
+	modeNormal tuiMode = iota
+	modeEdit
+	modeSearch
+	modeConfirmDelete
+)
+
+type (
+	// snapshotMsg carries the reloaded playlist and status.
+	snapshotMsg struct {
+		audios []*grpcapi.Audio
+		status *grpcapi.GetStatusResponse
+		at     time.Time
+	}
+	statusMsg struct {
+		status *grpcapi.GetStatusResponse
+		at     time.Time
+	}
+	errMsg      struct{ err error }
+	tickMsg     time.Time
+	eventMsg    struct{ event *grpcapi.Event }
+	watchMsg    struct{ err error }
+	watchingMsg struct{}
+)
+
+// tuiModel is the state of the interactive view.
+type tuiModel struct {
+	ctx    context.Context
+	client grpcapi.PlayerServiceClient
+
+	audios []*grpcapi.Audio
+	status *grpcapi.GetStatusResponse
+	// statusAt is the time the status was received,
+	// the position of the playing audio advances from it.
+	statusAt time.Time
+
+	// selected is the id of the audio under the cursor,
+	// it is kept by id so that the cursor follows the audio on reloads.
+	selected string
+	filter   string
+	mode     tuiMode
+	input    textinput.Model
+	bar      progress.Model
+	width    int
+
+	// lastPoll is the time the status was last polled.
+	lastPoll time.Time
+	online   bool
+	message  string
+}
+
+func runTUI(ctx context.Context, c grpcapi.PlayerServiceClient, _ printer, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, args)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	input := textinput.New()
+	input.CharLimit = 256
+	m := tuiModel{
+		ctx:    ctx,
+		client: c,
+		input:  input,
+		bar:    progress.New(progress.WithDefaultGradient(), progress.WithoutPercentage()),
+		width:  80,
+	}
+	prog := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx))
+	go watchEvents(ctx, c, prog.Send)
+	_, err := prog.Run()
+	return err
+}
+
+// watchEvents streams player events to send, the stream is reopened with
+// backoff after it breaks and resumed after the last received event.
+func watchEvents(ctx context.Context, c grpcapi.PlayerServiceClient, send func(tea.Msg)) {
+	var afterId uint64
+	delay := minReconnectDelay
+	for {
+		stream, err := c.WatchEvents(ctx, &grpcapi.WatchEventsRequest{
+			AfterId:           afterId,
+			HeartbeatInterval: durationpb.New(watchHeartbeat),
+		})
+		if err == nil {
+			// events missed while the stream was broken are replayed,
+			// but the playlist is reloaded anyway as the server may have restarted
+			send(watchingMsg{})
+			for {
+				var e *grpcapi.Event
+				e, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				delay = minReconnectDelay
+				if e.GetId() != 0 {
+					afterId = e.GetId()
+				}
+				if e.GetType() != grpcapi.EventType_HEARTBEAT {
+					send(eventMsg{event: e})
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if status.Code(err) == codes.Unimplemented {
+			// the server has events disabled, the status is polled instead
+			return
+		}
+		send(watchMsg{err: err})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.reload(), tick())
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(tickInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+// reload loads the playlist and the status.
+func (m tuiModel) reload() tea.Cmd {
+	return func() tea.Msg {
+		list, err := m.client.ListAudio(m.ctx, &grpcapi.ListAudioRequest{})
+		if err != nil {
+			return errMsg{err: err}
+		}
+		st, err := m.client.GetStatus(m.ctx, &grpcapi.GetStatusRequest{})
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return snapshotMsg{audios: list.GetAudio(), status: st, at: time.Now()}
+	}
+}
+
+func (m tuiModel) reloadStatus() tea.Cmd {
+	return func() tea.Msg {
+		st, err := m.client.GetStatus(m.ctx, &grpcapi.GetStatusRequest{})
+		if err != nil {
+			return errMsg{err: err}
+		}
+		return statusMsg{status: st, at: time.Now()}
+	}
+}
+
+// call makes the call and reloads the playlist and the status after it.
+func (m tuiModel) call(fn func(ctx context.Context) error) tea.Cmd {
+	return func() tea.Msg {
+		if err := fn(m.ctx); err != nil {
+			return errMsg{err: err}
+		}
+		return m.reload()()
+	}
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case tickMsg:
+		if time.Since(m.lastPoll) < statusPollInterval {
+			return m, tick()
+		}
+		m.lastPoll = time.Now()
+		if !m.online {
+			// the event stream may be unavailable, so reconnecting is checked here too
+			return m, tea.Batch(tick(), m.reload())
+		}
+		return m, tea.Batch(tick(), m.reloadStatus())
+	case snapshotMsg:
+		m.audios = msg.audios
+		m.status, m.statusAt = msg.status, msg.at
+		if !m.online {
+			m.online = true
+			m.message = ""
+		}
+		m.keepSelection()
+		return m, nil
+	case statusMsg:
+		m.status, m.statusAt = msg.status, msg.at
+		return m, nil
+	case errMsg:
+		m.setError(msg.err)
+		return m, nil
+	case eventMsg:
+		if t := msg.event.GetType(); t == grpcapi.EventType_STATE_CHANGED || t == grpcapi.EventType_TRACK_CHANGED {
+			return m, m.reloadStatus()
+		}
+		return m, m.reload()
+	case watchingMsg:
+		return m, m.reload()
+	case watchMsg:
+		m.online = false
+		m.setError(msg.err)
+		return m, nil
+	case tea.KeyMsg:
+		switch m.mode {
+		case modeEdit:
+			return m.updateEdit(msg)
+		case modeSearch:
+			return m.updateSearch(msg)
+		case modeConfirmDelete:
+			return m.updateConfirmDelete(msg)
+		default:
+			return m.updateNormal(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m tuiModel) updateNormal(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.client
+	m.message = ""
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		m.moveCursor(-1)
+	case "down", "j":
+		m.moveCursor(1)
+	case " ":
+		playing := m.status.GetState() == "playing"
+		return m, m.call(func(ctx context.Context) error {
+			var err error
+			if playing {
+				_, err = c.Pause(ctx, &grpcapi.PauseRequest{})
+			} else {
+				_, err = c.Play(ctx, &grpcapi.PlayRequest{})
+			}
+			return err
+		})
+	case "n":
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.Next(ctx, &grpcapi.NextRequest{})
+			return err
+		})
+	case "p":
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.Prev(ctx, &grpcapi.PrevRequest{})
+			return err
+		})
+	case "left", "right":
+		pos := m.position() + seekStep
+		if msg.String() == "left" {
+			pos = max(m.position()-seekStep, 0)
+		}
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.Seek(ctx, &grpcapi.SeekRequest{Position: durationpb.New(pos)})
+			return err
+		})
+	case "K", "shift+up", "J", "shift+down":
+		a, i := m.selectedAudio()
+		if a == nil {
+			break
+		}
+		if msg.String() == "K" || msg.String() == "shift+up" {
+			i--
+		} else {
+			i++
+		}
+		if i < 0 || i >= len(m.audios) {
+			break
+		}
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.MoveAudio(ctx, &grpcapi.MoveAudioRequest{Id: a.GetId(), Index: int32(i)})
+			return err
+		})
+	case "d", "delete":
+		if a, _ := m.selectedAudio(); a != nil {
+			m.mode = modeConfirmDelete
+		}
+	case "e", "enter":
+		if a, _ := m.selectedAudio(); a != nil {
+			m.mode = modeEdit
+			m.input.Prompt = "name: "
+			m.input.SetValue(a.GetName())
+			m.input.CursorEnd()
+			return m, m.input.Focus()
+		}
+	case "/":
+		m.mode = modeSearch
+		m.input.Prompt = "/"
+		m.input.SetValue(m.filter)
+		m.input.CursorEnd()
+		return m, m.input.Focus()
+	case "u":
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.Undo(ctx, &grpcapi.UndoRequest{})
+			return err
+		})
+	case "ctrl+r":
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.Redo(ctx, &grpcapi.RedoRequest{})
+			return err
+		})
+	case "r":
+		return m, m.reload()
+	}
+	return m, nil
+}
+
+func (m tuiModel) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeNormal
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		m.mode = modeNormal
+		m.input.Blur()
+		a, _ := m.selectedAudio()
+		name := strings.TrimSpace(m.input.Value())
+		if a == nil || name == "" || name == a.GetName() {
+			return m, nil
+		}
+		c := m.client
+		return m, m.call(func(ctx context.Context) error {
+			_, err := c.UpdateAudio(ctx, &grpcapi.UpdateAudioRequest{
+				Audio:           &grpcapi.Audio{Id: a.GetId(), Name: name, Duration: a.GetDuration()},
+				ExpectedVersion: a.GetVersion(),
+			})
+			return err
+		})
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = modeNormal
+		m.input.Blur()
+		m.filter = ""
+		m.keepSelection()
+		return m, nil
+	case "enter":
+		m.mode = modeNormal
+		m.input.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	m.filter = m.input.Value()
+	m.keepSelection()
+	return m, cmd
+}
+
+func (m tuiModel) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.mode = modeNormal
+	a, _ := m.selectedAudio()
+	if a == nil || (msg.String() != "y" && msg.String() != "Y") {
+		return m, nil
+	}
+	c := m.client
+	return m, m.call(func(ctx context.Context) error {
+		_, err := c.DeleteAudio(ctx, &grpcapi.DeleteAudioRequest{Id: a.GetId(), ExpectedVersion: a.GetVersion()})
+		return err
+	})
+}
+
+func (m *tuiModel) setError(err error) {
+	if st, ok := status.FromError(err); ok {
+		if st.Code() == codes.Unavailable {
+			m.online = false
+		}
+		m.message = fmt.Sprintf("%s: %s", st.Code(), st.Message())
+		return
+	}
+	m.message = err.Error()
+}
+
+// visible returns the audios matching the filter.
+func (m tuiModel) visible() []*grpcapi.Audio {
+	if m.filter == "" {
+		return m.audios
+	}
+	filter := strings.ToLower(m.filter)
+	var res []*grpcapi.Audio
+	for _, a := range m.audios {
+		if strings.Contains(strings.ToLower(a.GetName()), filter) {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// selectedAudio returns the audio under the cursor and its index in the playlist.
+func (m tuiModel) selectedAudio() (*grpcapi.Audio, int) {
+	for i, a := range m.audios {
+		if a.GetId() == m.selected {
+			return a, i
+		}
+	}
+	return nil, -1
+}
+
+func (m *tuiModel) moveCursor(delta int) {
+	vis := m.visible()
+	if len(vis) == 0 {
+		return
+	}
+	i := 0
+	for j, a := range vis {
+		if a.GetId() == m.selected {
+			i = j + delta
+			break
+		}
+	}
+	m.selected = vis[min(max(i, 0), len(vis)-1)].GetId()
+}
+
+// keepSelection moves the cursor to the first visible audio
+// if the selected one is not visible anymore.
+func (m *tuiModel) keepSelection() {
+	vis := m.visible()
+	for _, a := range vis {
+		if a.GetId() == m.selected {
+			return
+		}
+	}
+	m.selected = ""
+	if len(vis) > 0 {
+		m.selected = vis[0].GetId()
+	}
+}
+
+// position returns the playback position of the current audio at the moment.
+func (m tuiModel) position() time.Duration {
+	pos := m.status.GetPosition().AsDuration()
+	if m.status.GetState() == "playing" && m.online {
+		pos += time.Since(m.statusAt)
+	}
+	if d := m.status.GetAudio().GetDuration().AsDuration(); pos > d {
+		pos = d
+	}
+	return pos
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+
+	current := m.status.GetAudio()
+	state := m.status.GetState()
+	if state == "" {
+		state = "-"
+	}
+	fmt.Fprintf(&b, "%s  %s\n", titleStyle.Render(state), dash(current.GetName()))
+	pos, dur := m.position(), current.GetDuration().AsDuration()
+	var percent float64
+	if dur > 0 {
+		percent = float64(pos) / float64(dur)
+	}
+	m.bar.Width = max(m.width-20, 10)
+	fmt.Fprintf(&b, "%s %s / %s\n\n", m.bar.ViewAs(percent), formatDuration(pos), formatDuration(dur))
+
+	vis := m.visible()
+	if len(vis) == 0 {
+		b.WriteString(dimStyle.Render("no audios") + "\n")
+	}
+	for _, a := range vis {
+		line := fmt.Sprintf("  %-*s %8s", max(m.width-14, 10), a.GetName(), formatDuration(a.GetDuration().AsDuration()))
+		if a.GetId() == current.GetId() {
+			line = currentStyle.Render("▶" + line[len(" "):])
+		}
+		if a.GetId() == m.selected {
+			line = cursorStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+
+	switch m.mode {
+	case modeEdit, modeSearch:
+		b.WriteString(m.input.View() + "\n")
+	case modeConfirmDelete:
+		a, _ := m.selectedAudio()
+		fmt.Fprintf(&b, "delete %q? y/n\n", a.GetName())
+	default:
+		if m.filter != "" {
+			b.WriteString(dimStyle.Render("/"+m.filter) + "\n")
+		}
+	}
+
+	switch {
+	case !m.online:
+		msg := "offline, reconnecting"
+		if m.message != "" {
+			msg += ": " + m.message
+		}
+		b.WriteString(errorStyle.Render(msg) + "\n")
+	case m.message != "":
+		b.WriteString(errorStyle.Render(m.message) + "\n")
+	}
+	b.WriteString(dimStyle.Render("space play  n/p next/prev  ←/→ seek  K/J move  e edit  d del  / find  q quit") + "\n")
+	b.WriteString(dimStyle.Render("u undo  ctrl+r redo"))
+	return b.String()
+}
+
+// formatDuration formats d as m:ss.
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,155 @@
+// Command loadgen drives a mix of player and playlist calls with concurrent
+// workers against a server and reports throughput and latency per call.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/tlsconfig"
+	"github.com/Karzoug/gocloudcamp/pkg/playerclient"
+	"golang.org/x/time/rate"
+)
+
+const defaultMix = "status=40,list=10,get=15,create=10,update=10,delete=5,move=2,play=3,pause=3,next=1,prev=1"
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	addr := flag.String("addr", "localhost:50052", "the address to connect to")
+	apiKey := flag.String("api-key", "", "API key to authenticate with")
+	token := flag.String("token", "", "JWT bearer token to authenticate with")
+	useTLS := flag.Bool("tls", false, "whether to connect with TLS")
+	tlsCA := flag.String("tls-ca", "", "PEM file with CA bundle to verify the server certificate (empty value - system roots)")
+	tlsCert := flag.String("tls-cert", "", "PEM file with client certificate for mutual TLS")
+	tlsKey := flag.String("tls-key", "", "PEM file with client private key for mutual TLS")
+	tlsServerName := flag.String("tls-server-name", "", "server name to verify the server certificate against")
+	workers := flag.Int("workers", 10, "number of concurrent workers")
+	duration := flag.Duration("duration", 30*time.Second, "duration of the test")
+	qps := flag.Float64("rate", 0, "maximal total number of calls per second (0 - unlimited)")
+	mixFlag := flag.String("mix", defaultMix, "comma-separated weights of calls: "+opNames())
+	timeout := flag.Duration("timeout", 5*time.Second, "timeout of a call")
+	interval := flag.Duration("interval", 5*time.Second, "interval of progress reports and error rate checks")
+	maxErrorRate := flag.Float64("max-error-rate", 0.05, "share of failed calls in an interval reported as an error spike")
+	flag.Parse()
+
+	mix, err := parseMix(*mixFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *workers < 1 {
+		fmt.Fprintln(os.Stderr, "workers must be positive")
+		return 2
+	}
+
+	opts := []playerclient.Option{
+		// every attempt is measured, so calls are not retried
+		playerclient.WithRetryPolicy(playerclient.RetryPolicy{MaxAttempts: 1}),
+		playerclient.WithAPIKey(*apiKey),
+		playerclient.WithToken(*token),
+	}
+	if *useTLS {
+		tlsCfg, err := tlsconfig.Client(*tlsCA, *tlsCert, *tlsKey, *tlsServerName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "create TLS config error: %v\n", err)
+			return 1
+		}
+		opts = append(opts, playerclient.WithTLS(tlsCfg))
+	}
+	client, err := playerclient.New(*addr, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	ids := &idPool{}
+	seedCtx, seedCancel := context.WithTimeout(ctx, *timeout)
+	audios, _, err := client.ListAudio(seedCtx)
+	seedCancel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "list audios error: %v\n", err)
+		return 1
+	}
+	for _, a := range audios {
+		ids.add(a.Id)
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if *qps > 0 {
+		limiter = rate.NewLimiter(rate.Limit(*qps), 1)
+	}
+
+	st := newStats()
+	runCtx, runCancel := context.WithTimeout(ctx, *duration)
+	defer runCancel()
+
+	fmt.Printf("running %d workers for %s against %s\n", *workers, *duration, *addr)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			w := newWorker(client, ids, mix, st, *timeout, seed)
+			for limiter.Wait(runCtx) == nil {
+				w.step(runCtx)
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+
+	spikes := watchErrorRate(runCtx, st, *interval, *maxErrorRate)
+	wg.Wait()
+	elapsed := time.Since(start)
+	runCancel()
+	n := <-spikes
+
+	st.report(os.Stdout, elapsed)
+	if n > 0 {
+		fmt.Printf("\nerror rate exceeded %.1f%% in %d interval(s)\n", *maxErrorRate*100, n)
+		return 1
+	}
+	return 0
+}
+
+// watchErrorRate prints the progress every interval and reports intervals
+// with the share of failed calls above maxRate. The number of such intervals
+// is sent to the returned channel when ctx is done.
+func watchErrorRate(ctx context.Context, st *stats, interval time.Duration, maxRate float64) <-chan int {
+	res := make(chan int, 1)
+	go func() {
+		var spikes int
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				res <- spikes
+				return
+			case <-ticker.C:
+			}
+			calls, errs, codes := st.takeInterval()
+			if calls == 0 {
+				continue
+			}
+			errRate := float64(errs) / float64(calls)
+			fmt.Printf("%8.0f calls/s  errors %5.1f%%\n", float64(calls)/interval.Seconds(), errRate*100)
+			if errRate > maxRate {
+				spikes++
+				fmt.Printf("error spike: %v\n", codes)
+			}
+		}
+	}()
+	return res
+}
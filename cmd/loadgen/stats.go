@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// opStats are the results of calls of one operation.
+type opStats struct {
+	latencies []time.Duration
+	errors    map[codes.Code]int
+}
+
+// stats collects the results of calls of all workers.
+type stats struct {
+	mtx sync.Mutex
+	ops map[string]*opStats
+
+	// interval counters are reset by takeInterval
+	intervalCalls  int
+	intervalErrors map[codes.Code]int
+}
+
+func newStats() *stats {
+	return &stats{
+		ops:            make(map[string]*opStats),
+		intervalErrors: make(map[codes.Code]int),
+	}
+}
+
+func (s *stats) record(op string, latency time.Duration, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	o, ok := s.ops[op]
+	if !ok {
+		o = &opStats{errors: make(map[codes.Code]int)}
+		s.ops[op] = o
+	}
+	o.latencies = append(o.latencies, latency)
+	s.intervalCalls++
+	if err != nil {
+		code := status.Code(err)
+		o.errors[code]++
+		s.intervalErrors[code]++
+	}
+}
+
+// takeInterval returns the number of calls and failed ones by code
+// since the previous call.
+func (s *stats) takeInterval() (calls, errs int, byCode map[codes.Code]int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	calls, byCode = s.intervalCalls, s.intervalErrors
+	for _, n := range byCode {
+		errs += n
+	}
+	s.intervalCalls = 0
+	s.intervalErrors = make(map[codes.Code]int)
+	return calls, errs, byCode
+}
+
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	names := make([]string, 0, len(s.ops))
+	for name := range s.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "CALL\tCOUNT\tCALLS/S\tERRORS\tP50\tP90\tP99\tMAX\t")
+	var total, totalErrors int
+	for _, name := range names {
+		o := s.ops[name]
+		sort.Slice(o.latencies, func(i, j int) bool { return o.latencies[i] < o.latencies[j] })
+		var errs int
+		for _, n := range o.errors {
+			errs += n
+		}
+		total += len(o.latencies)
+		totalErrors += errs
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%d\t%s\t%s\t%s\t%s\t\n", name, len(o.latencies),
+			float64(len(o.latencies))/elapsed.Seconds(), errs,
+			percentile(o.latencies, 0.5), percentile(o.latencies, 0.9), percentile(o.latencies, 0.99),
+			percentile(o.latencies, 1))
+	}
+	fmt.Fprintf(tw, "total\t%d\t%.1f\t%d\t\t\t\t\t\n", total, float64(total)/elapsed.Seconds(), totalErrors)
+	tw.Flush()
+
+	for _, name := range names {
+		if errs := s.ops[name].errors; len(errs) > 0 {
+			fmt.Fprintf(w, "%s errors: %v\n", name, errs)
+		}
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p+0.5) - 1
+	i = min(max(i, 0), len(sorted)-1)
+	return sorted[i].Round(time.Microsecond)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/playerclient"
+)
+
+// op is a call made by workers.
+type op struct {
+	name string
+	// needsAudio is set for calls of an existing audio,
+	// they are replaced with create while there are no audios.
+	needsAudio bool
+	call       func(ctx context.Context, w *worker) error
+}
+
+var ops = []op{
+	{"status", false, func(ctx context.Context, w *worker) error {
+		_, err := w.client.Status(ctx)
+		return err
+	}},
+	{"list", false, func(ctx context.Context, w *worker) error {
+		_, _, err := w.client.ListAudio(ctx)
+		return err
+	}},
+	{"get", true, func(ctx context.Context, w *worker) error {
+		_, err := w.client.GetAudio(ctx, w.ids.random(w.rnd))
+		return err
+	}},
+	{"create", false, func(ctx context.Context, w *worker) error {
+		a, err := w.client.CreateAudio(ctx, fmt.Sprintf("loadgen %d", w.rnd.Int()), time.Duration(30+w.rnd.Intn(300))*time.Second)
+		if err == nil {
+			w.ids.add(a.Id)
+		}
+		return err
+	}},
+	{"update", true, func(ctx context.Context, w *worker) error {
+		_, err := w.client.UpdateAudio(ctx, playerclient.Audio{
+			Id:       w.ids.random(w.rnd),
+			Name:     fmt.Sprintf("loadgen %d", w.rnd.Int()),
+			Duration: time.Duration(30+w.rnd.Intn(300)) * time.Second,
+		}, 0)
+		return err
+	}},
+	{"delete", true, func(ctx context.Context, w *worker) error {
+		id := w.ids.take(w.rnd)
+		err := w.client.DeleteAudio(ctx, id, 0)
+		if err != nil && !errors.Is(err, playerclient.ErrNotFound) {
+			// e.g. a timeout, the audio may be deleted later
+			w.ids.add(id)
+		}
+		return err
+	}},
+	{"move", true, func(ctx context.Context, w *worker) error {
+		// the index may be past the end as other workers delete audios
+		return w.client.MoveAudio(ctx, w.ids.random(w.rnd), w.rnd.Intn(w.ids.len()+1))
+	}},
+	{"play", false, func(ctx context.Context, w *worker) error {
+		return w.client.Play(ctx)
+	}},
+	{"pause", false, func(ctx context.Context, w *worker) error {
+		return w.client.Pause(ctx)
+	}},
+	{"next", false, func(ctx context.Context, w *worker) error {
+		return w.client.Next(ctx)
+	}},
+	{"prev", false, func(ctx context.Context, w *worker) error {
+		return w.client.Prev(ctx)
+	}},
+}
+
+func opNames() string {
+	names := make([]string, 0, len(ops))
+	for _, o := range ops {
+		names = append(names, o.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func findOp(name string) (op, bool) {
+	for _, o := range ops {
+		if o.name == name {
+			return o, true
+		}
+	}
+	return op{}, false
+}
+
+// mix is a weighted choice of operations.
+type mix struct {
+	ops     []op
+	weights []int // cumulative
+}
+
+// parseMix parses weights of operations in form of "status=10,create=2".
+func parseMix(s string) (mix, error) {
+	var m mix
+	var total int
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return mix{}, fmt.Errorf("invalid mix %q: want name=weight", part)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return mix{}, fmt.Errorf("invalid mix %q: weight must be a non-negative integer", part)
+		}
+		o, ok := findOp(name)
+		if !ok {
+			return mix{}, fmt.Errorf("invalid mix %q: unknown call, want one of %s", part, opNames())
+		}
+		if weight == 0 {
+			continue
+		}
+		total += weight
+		m.ops = append(m.ops, o)
+		m.weights = append(m.weights, total)
+	}
+	if total == 0 {
+		return mix{}, fmt.Errorf("invalid mix %q: no calls", s)
+	}
+	return m, nil
+}
+
+func (m mix) pick(rnd *rand.Rand) op {
+	n := rnd.Intn(m.weights[len(m.weights)-1])
+	return m.ops[sort.SearchInts(m.weights, n+1)]
+}
+
+// worker makes calls one after another.
+type worker struct {
+	client  *playerclient.Client
+	ids     *idPool
+	mix     mix
+	stats   *stats
+	timeout time.Duration
+	rnd     *rand.Rand
+}
+
+func newWorker(client *playerclient.Client, ids *idPool, m mix, st *stats, timeout time.Duration, seed int64) *worker {
+	return &worker{
+		client:  client,
+		ids:     ids,
+		mix:     m,
+		stats:   st,
+		timeout: timeout,
+		rnd:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (w *worker) step(ctx context.Context) {
+	o := w.mix.pick(w.rnd)
+	if o.needsAudio && w.ids.len() == 0 {
+		o, _ = findOp("create")
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+	start := time.Now()
+	err := o.call(callCtx, w)
+	if ctx.Err() != nil {
+		// the call was interrupted by the end of the test
+		return
+	}
+	w.stats.record(o.name, time.Since(start), err)
+}
+
+// idPool holds ids of known audios.
+type idPool struct {
+	mtx sync.Mutex
+	ids []string
+}
+
+func (p *idPool) add(id string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.ids = append(p.ids, id)
+}
+
+func (p *idPool) len() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.ids)
+}
+
+// random returns a random id, empty if there are none.
+func (p *idPool) random(rnd *rand.Rand) string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if len(p.ids) == 0 {
+		return ""
+	}
+	return p.ids[rnd.Intn(len(p.ids))]
+}
+
+// take removes a random id from the pool and returns it, empty if there are none.
+func (p *idPool) take(rnd *rand.Rand) string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if len(p.ids) == 0 {
+		return ""
+	}
+	i := rnd.Intn(len(p.ids))
+	id := p.ids[i]
+	p.ids[i] = p.ids[len(p.ids)-1]
+	p.ids = p.ids[:len(p.ids)-1]
+	return id
+}
@@ -2,20 +2,52 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/Karzoug/gocloudcamp/internal/audit"
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"github.com/Karzoug/gocloudcamp/internal/authz"
 	"github.com/Karzoug/gocloudcamp/internal/config"
-	"github.com/Karzoug/gocloudcamp/internal/grpcapi"
+	"github.com/Karzoug/gocloudcamp/internal/events"
+	"github.com/Karzoug/gocloudcamp/internal/gateway"
+	"github.com/Karzoug/gocloudcamp/internal/logging"
 	"github.com/Karzoug/gocloudcamp/internal/player"
 	"github.com/Karzoug/gocloudcamp/internal/playlist"
 	"github.com/Karzoug/gocloudcamp/internal/playlist/file"
 	"github.com/Karzoug/gocloudcamp/internal/playlist/memory"
+	"github.com/Karzoug/gocloudcamp/internal/ratelimit"
 	"github.com/Karzoug/gocloudcamp/internal/server"
+	"github.com/Karzoug/gocloudcamp/internal/tlsconfig"
+	"github.com/Karzoug/gocloudcamp/internal/tracing"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/admin"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// gatewayBufferSize is the buffer size of the in-memory connection of the gateway.
+	gatewayBufferSize = 1 << 20
+
+	// shutdownTimeout limits the graceful stop of gRPC servers,
+	// calls that have not completed by then are canceled.
+	shutdownTimeout = 10 * time.Second
 )
 
 func main() {
@@ -27,36 +59,266 @@ func main() {
 		log.Fatalf("load config error: %v", err)
 	}
 
+	logger, err := logging.New(os.Stderr, cfg)
+	if err != nil {
+		log.Fatalf("create logger error: %v", err)
+	}
+	slog.SetDefault(logger)
+	fatal := func(msg string, err error) {
+		logger.Error(msg, slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Init(ctx, cfg)
+	if err != nil {
+		fatal("init tracing error", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("shutdown tracing error", slog.Any("error", err))
+		}
+	}()
+
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Port()))
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		fatal("failed to listen", err)
+	}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		otelgrpc.UnaryServerInterceptor(),
+		server.UnaryRequestIdInterceptor(),
+		server.UnaryLoggingInterceptor(logger),
+		server.UnaryMetricsInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		server.StreamRequestIdInterceptor(),
+		server.StreamMetricsInterceptor(),
+	}
+	if cfg.IsAuthEnabled() {
+		authns, err := authenticators(cfg)
+		if err != nil {
+			fatal("create authenticators error", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, auth.UnaryServerInterceptor(authns...))
+		streamInterceptors = append(streamInterceptors, auth.StreamServerInterceptor(authns...))
+	}
+	bus := events.NewBus(cfg.EventsBuffer())
+	serverOpts := []server.Option{server.WithEvents(bus)}
+	if cfg.AuditFile() != "" {
+		auditLog, err := audit.New(cfg)
+		if err != nil {
+			fatal("create audit log error", err)
+		}
+		defer auditLog.Close()
+		// calls rejected by rate limits and authorization are recorded too
+		unaryInterceptors = append(unaryInterceptors, audit.UnaryServerInterceptor(auditLog, server.IsMutating, logger))
+		serverOpts = append(serverOpts, server.WithAudit(auditLog))
+	}
+	if cfg.RateLimitsFile() != "" {
+		limits, err := ratelimit.LoadLimits(cfg.RateLimitsFile())
+		if err != nil {
+			fatal("load rate limits error", err)
+		}
+		limiter := ratelimit.New(limits)
+		go limiter.Cleanup(ctx)
+		unaryInterceptors = append(unaryInterceptors, limiter.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, limiter.StreamServerInterceptor())
+	}
+	if cfg.PolicyFile() != "" {
+		authorizer, err := authz.New(cfg.PolicyFile(), logger)
+		if err != nil {
+			fatal("create authorizer error", err)
+		}
+		go authorizer.Watch(ctx)
+		unaryInterceptors = append(unaryInterceptors, authorizer.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authorizer.StreamServerInterceptor())
+	}
+	// domain errors are converted innermost, so that the preceding
+	// interceptors observe the resulting status codes
+	unaryInterceptors = append(unaryInterceptors,
+		server.UnaryValidationInterceptor(),
+		server.UnaryErrorInterceptor(),
+	)
+	streamInterceptors = append(streamInterceptors,
+		server.StreamValidationInterceptor(),
+		server.StreamErrorInterceptor(),
+	)
+
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	}
-	s := grpc.NewServer()
+	var tlsSrv *tlsconfig.Server
+	if cfg.IsTLSEnabled() {
+		tlsSrv, err = tlsconfig.NewServer(cfg, logger)
+		if err != nil {
+			fatal("create TLS config error", err)
+		}
+		go tlsSrv.Watch(ctx)
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsSrv.Config())))
+	}
+	s := grpc.NewServer(grpcOpts...)
 
 	var pl playlist.Playlist
+	plOpts := []memory.Option{memory.WithTrashRetention(cfg.TrashRetention())}
+	playerOpts := []player.Option{
+		player.WithCoalesceWindow(cfg.CoalesceWindow()),
+		player.WithEvents(bus),
+	}
 	if cfg.IsStoreInMemory() {
-		pl = memory.New()
+		pl = memory.New(logger, plOpts...)
 	} else {
-		var err error
-		pl, err = file.New(cfg)
+		fp, err := file.New(cfg, logger, plOpts...)
 		if err != nil {
-			log.Fatalf("create playlist error: %v", err)
+			fatal("create playlist error", err)
 		}
+		pl = fp
+		playerOpts = append(playerOpts, player.WithSleepTimerStore(fp))
 	}
 
-	player := player.New(pl)
-	defer player.Close()
+	if cfg.HistoryDepth() > 0 {
+		h := playlist.NewHistory(pl, cfg.HistoryDepth(), auth.Subject)
+		serverOpts = append(serverOpts, server.WithHistory(h))
+		pl = h
+	}
+
+	p := player.New(playlist.WithTracing(pl), logger, playerOpts...)
+	defer p.Close()
+
+	playerSrv := server.New(p, logger, serverOpts...)
+	grpcapi.RegisterPlayerServiceServer(s, playerSrv)
+	healthpb.RegisterHealthServer(s, server.NewHealthServer(ctx, p, logger))
+	if cfg.Reflection() {
+		reflection.Register(s)
+	}
+	cleanupAdmin, err := admin.Register(s)
+	if err != nil {
+		fatal("register admin services error", err)
+	}
+	defer cleanupAdmin()
+
+	prometheus.MustRegister(player.NewCollector(p))
+	if cfg.MetricsAddr() != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		metricsSrv := &http.Server{Addr: cfg.MetricsAddr(), Handler: mux}
+		go func() {
+			logger.Info("metrics server listening", slog.String("addr", metricsSrv.Addr))
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("failed to serve metrics", err)
+			}
+		}()
+		defer metricsSrv.Close()
+	}
 
-	grpcapi.RegisterPlayerServiceServer(s, server.New(player))
+	if cfg.GatewayAddr() != "" {
+		// the gateway calls its own gRPC server in memory, so that REST calls
+		// pass through the same interceptors, but not through TLS, and the
+		// peer of the calls is the HTTP client instead of the connection
+		gwS := grpc.NewServer(
+			grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{gateway.UnaryPeerInterceptor()}, unaryInterceptors...)...),
+			grpc.ChainStreamInterceptor(append([]grpc.StreamServerInterceptor{gateway.StreamPeerInterceptor()}, streamInterceptors...)...),
+		)
+		grpcapi.RegisterPlayerServiceServer(gwS, playerSrv)
+		defer stopGracefully(gwS, logger)
+		gwLis := bufconn.Listen(gatewayBufferSize)
+		go func() {
+			if err := gwS.Serve(gwLis); err != nil {
+				fatal("failed to serve gateway connections", err)
+			}
+		}()
+		conn, err := grpc.DialContext(ctx, "bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return gwLis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			fatal("dial gateway connection error", err)
+		}
+		defer conn.Close()
+		gw, err := gateway.New(ctx, conn, cfg.CORSOrigins())
+		if err != nil {
+			fatal("create gateway error", err)
+		}
+		gwSrv := &http.Server{Addr: cfg.GatewayAddr(), Handler: gw}
+		go func() {
+			logger.Info("gateway listening", slog.String("addr", gwSrv.Addr))
+			if err := listenAndServe(gwSrv, tlsSrv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("failed to serve gateway", err)
+			}
+		}()
+		defer gwSrv.Close()
+	}
+
+	if cfg.GRPCWebAddr() != "" {
+		webSrv := &http.Server{Addr: cfg.GRPCWebAddr(), Handler: gateway.GRPCWeb(s, cfg.CORSOrigins())}
+		go func() {
+			logger.Info("gRPC-Web server listening", slog.String("addr", webSrv.Addr))
+			if err := listenAndServe(webSrv, tlsSrv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("failed to serve gRPC-Web", err)
+			}
+		}()
+		defer webSrv.Close()
+	}
 
 	go func() {
-		log.Printf("server listening at %v", lis.Addr())
+		logger.Info("server listening", slog.String("addr", lis.Addr().String()))
 		if err := s.Serve(lis); err != nil {
-			log.Fatalf("failed to serve: %v", err)
+			fatal("failed to serve", err)
 		}
 	}()
 
 	<-ctx.Done()
-	log.Println("stop the server gracefully")
-	s.GracefulStop()
+	logger.Info("stop the server gracefully")
+	// event streams last until the client leaves, so they are ended first
+	bus.Close()
+	stopGracefully(s, logger)
+}
+
+// stopGracefully stops s gracefully, but not longer than shutdownTimeout.
+func stopGracefully(s *grpc.Server, logger *slog.Logger) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		logger.Warn("graceful stop timed out, cancel the remaining calls")
+		s.Stop()
+	}
+}
+
+// listenAndServe serves srv with TLS, if tlsSrv is not nil.
+func listenAndServe(srv *http.Server, tlsSrv *tlsconfig.Server) error {
+	if tlsSrv == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = tlsSrv.Config()
+	return srv.ListenAndServeTLS("", "")
+}
+
+func authenticators(cfg *config.Config) ([]auth.Authenticator, error) {
+	var authns []auth.Authenticator
+	if cfg.APIKeysFile() != "" {
+		a, err := auth.NewAPIKeyAuthenticator(cfg.APIKeysFile())
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, a)
+	}
+	if cfg.IsJWTEnabled() {
+		a, err := auth.NewJWTAuthenticator(cfg)
+		if err != nil {
+			return nil, err
+		}
+		authns = append(authns, a)
+	}
+	// client certificates are checked last, so that tokens
+	// and keys take precedence over the certificate identity
+	if cfg.IsMutualTLSEnabled() {
+		authns = append(authns, auth.TLSAuthenticator{})
+	}
+	return authns, nil
 }
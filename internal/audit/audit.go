@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// ErrDisabled is returned when events are requested but the audit log is not configured.
+var ErrDisabled = errors.New("audit is disabled")
+
+// Event is a record about a mutating operation.
+type Event struct {
+	Id        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Principal string    `json:"principal,omitempty"`
+	Peer      string    `json:"peer,omitempty"`
+	Method    string    `json:"method"`
+	// Request is a summary of the request payload.
+	Request string `json:"request,omitempty"`
+	Code    string `json:"code"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Filter selects events. Zero values of fields mean no restriction.
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	Principal string
+	// Method is the full method name, e.g. /grpcapi.PlayerService/DeleteAudio.
+	Method string
+	// Limit is the maximal number of the latest events to return.
+	Limit int
+}
+
+func (f Filter) match(e Event) bool {
+	return (f.From.IsZero() || !e.Time.Before(f.From)) &&
+		(f.To.IsZero() || e.Time.Before(f.To)) &&
+		(f.Principal == "" || e.Principal == f.Principal) &&
+		(f.Method == "" || e.Method == f.Method)
+}
+
+type auditConfig interface {
+	AuditFile() string
+	// AuditMaxSize returns the size in bytes after which the file is rotated.
+	AuditMaxSize() int64
+	// AuditMaxBackups returns the number of rotated files to keep.
+	AuditMaxBackups() int
+}
+
+// Log is an append-only JSON lines file of events. When the file grows
+// over the maximal size, it is renamed to <file>.1, the previous <file>.1
+// to <file>.2 and so on, files over the maximal number of backups are removed.
+type Log struct {
+	filename   string
+	maxSize    int64
+	maxBackups int
+
+	mtx  sync.Mutex
+	file *os.File
+	size int64
+}
+
+func New(cfg auditConfig) (*Log, error) {
+	l := &Log{
+		filename:   cfg.AuditFile(),
+		maxSize:    cfg.AuditMaxSize(),
+		maxBackups: cfg.AuditMaxBackups(),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit file error: %w", err)
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat audit file error: %w", err)
+	}
+	l.file = file
+	l.size = fi.Size()
+	return nil
+}
+
+// Record appends the event to the log, generating its id if it is empty.
+func (l *Log) Record(e Event) error {
+	if e.Id == "" {
+		e.Id = xid.New().String()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal audit event error: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("write audit event error: %w", err)
+	}
+	return nil
+}
+
+func (l *Log) backupName(i int) string {
+	return fmt.Sprintf("%s.%d", l.filename, i)
+}
+
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("close audit file error: %w", err)
+	}
+	if err := os.Remove(l.backupName(l.maxBackups)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove audit backup error: %w", err)
+	}
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(l.backupName(i), l.backupName(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("rotate audit backup error: %w", err)
+		}
+	}
+	if l.maxBackups > 0 {
+		if err := os.Rename(l.filename, l.backupName(1)); err != nil {
+			return fmt.Errorf("rotate audit file error: %w", err)
+		}
+	} else if err := os.Truncate(l.filename, 0); err != nil {
+		return fmt.Errorf("truncate audit file error: %w", err)
+	}
+	return l.open()
+}
+
+// Query returns events matching the filter, ordered from the oldest to the latest.
+func (l *Log) Query(f Filter) ([]Event, error) {
+	files, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, sf := range files {
+			sf.file.Close()
+		}
+	}()
+
+	var events []Event
+	for _, sf := range files {
+		if err := readEvents(sf.r, f, &events); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[len(events)-f.Limit:]
+	}
+	return events, nil
+}
+
+// snapshotFile is a log file opened by snapshot.
+type snapshotFile struct {
+	file *os.File
+	r    io.Reader
+}
+
+// snapshot opens the log files from the oldest to the current one. Only opening
+// is done under the lock, so that reading does not block Record: rotation renames
+// or removes files, but the opened ones are kept, and the current file is read
+// up to its size at the time of the snapshot.
+func (l *Log) snapshot() ([]snapshotFile, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var files []snapshotFile
+	for i := l.maxBackups; i >= 0; i-- {
+		name := l.filename
+		if i > 0 {
+			name = l.backupName(i)
+		}
+		file, err := os.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			for _, sf := range files {
+				sf.file.Close()
+			}
+			return nil, fmt.Errorf("open audit file error: %w", err)
+		}
+		sf := snapshotFile{file: file, r: file}
+		if i == 0 {
+			sf.r = io.LimitReader(file, l.size)
+		}
+		files = append(files, sf)
+	}
+	return files, nil
+}
+
+func readEvents(r io.Reader, f Filter, events *[]Event) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			// a line may be broken by a crash while writing
+			continue
+		}
+		if f.match(e) {
+			*events = append(*events, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read audit file error: %w", err)
+	}
+	return nil
+}
+
+func (l *Log) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.file.Close()
+}
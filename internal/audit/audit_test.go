@@ -0,0 +1,226 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	file       string
+	maxSize    int64
+	maxBackups int
+}
+
+func (c testConfig) AuditFile() string    { return c.file }
+func (c testConfig) AuditMaxSize() int64  { return c.maxSize }
+func (c testConfig) AuditMaxBackups() int { return c.maxBackups }
+
+func newTestLog(t *testing.T, maxSize int64, maxBackups int) *Log {
+	t.Helper()
+	l, err := New(testConfig{file: filepath.Join(t.TempDir(), "audit.log"), maxSize: maxSize, maxBackups: maxBackups})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func ids(events []Event) []string {
+	var res []string
+	for _, e := range events {
+		res = append(res, e.Id)
+	}
+	return res
+}
+
+// lineSize is the size of a line of the event recorded by recordN.
+func lineSize(t *testing.T) int64 {
+	t.Helper()
+	l := newTestLog(t, 0, 0)
+	recordN(t, l, 1)
+	fi, err := os.Stat(l.filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+// recordN records n events with ids e0, e1, ... of the same size.
+func recordN(t *testing.T, l *Log, n int) {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		e := Event{
+			Id:     fmt.Sprintf("e%d", i),
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Method: "/grpcapi.PlayerService/Play",
+			Code:   "OK",
+		}
+		if err := l.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLog_Rotation(t *testing.T) {
+	size := lineSize(t)
+	tests := []struct {
+		name       string
+		maxSize    int64
+		maxBackups int
+		records    int
+		// files are the numbers of events in the current file and the backups
+		files []int
+		want  []string
+	}{
+		{name: "no rotation", maxSize: 0, maxBackups: 2, records: 5, files: []int{5}, want: []string{"e0", "e1", "e2", "e3", "e4"}},
+		{name: "under the size", maxSize: 3 * size, maxBackups: 2, records: 3, files: []int{3}, want: []string{"e0", "e1", "e2"}},
+		{name: "one backup", maxSize: 3 * size, maxBackups: 2, records: 4, files: []int{1, 3}, want: []string{"e0", "e1", "e2", "e3"}},
+		{
+			name:       "oldest backup removed",
+			maxSize:    2 * size,
+			maxBackups: 2,
+			records:    7,
+			files:      []int{1, 2, 2},
+			want:       []string{"e2", "e3", "e4", "e5", "e6"},
+		},
+		{name: "truncated without backups", maxSize: 2 * size, maxBackups: 0, records: 5, files: []int{1}, want: []string{"e4"}},
+		{name: "event over the size", maxSize: size / 2, maxBackups: 1, records: 3, files: []int{1, 1}, want: []string{"e1", "e2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLog(t, tt.maxSize, tt.maxBackups)
+			recordN(t, l, tt.records)
+
+			for i := 0; i <= tt.maxBackups+1; i++ {
+				name := l.filename
+				if i > 0 {
+					name = l.backupName(i)
+				}
+				fi, err := os.Stat(name)
+				if i >= len(tt.files) {
+					if err == nil {
+						t.Errorf("%s exists", filepath.Base(name))
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, want := fi.Size(), int64(tt.files[i])*size; got != want {
+					t.Errorf("size of %s = %d, want %d", filepath.Base(name), got, want)
+				}
+			}
+
+			events, err := l.Query(Filter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(events); !slices.Equal(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLog_Query(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const (
+		play        = "/grpcapi.PlayerService/Play"
+		deleteAudio = "/grpcapi.PlayerService/DeleteAudio"
+	)
+	records := []Event{
+		{Id: "1", Time: start, Principal: "alice", Method: play},
+		{Id: "2", Time: start.Add(time.Minute), Principal: "bob", Method: deleteAudio},
+		{Id: "3", Time: start.Add(2 * time.Minute), Principal: "alice", Method: deleteAudio},
+		{Id: "4", Time: start.Add(3 * time.Minute), Method: play},
+		{Id: "5", Time: start.Add(4 * time.Minute), Principal: "bob", Method: play},
+	}
+	// events are spread over the current file and the backups
+	l := newTestLog(t, 150, 10)
+	for _, e := range records {
+		e.Code = "OK"
+		if err := l.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(l.backupName(2)); err != nil {
+		t.Fatalf("events are not rotated: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "all", want: []string{"1", "2", "3", "4", "5"}},
+		{name: "from is inclusive", filter: Filter{From: start.Add(3 * time.Minute)}, want: []string{"4", "5"}},
+		{name: "to is exclusive", filter: Filter{To: start.Add(2 * time.Minute)}, want: []string{"1", "2"}},
+		{name: "range", filter: Filter{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)}, want: []string{"2", "3"}},
+		{name: "principal", filter: Filter{Principal: "alice"}, want: []string{"1", "3"}},
+		{name: "method", filter: Filter{Method: deleteAudio}, want: []string{"2", "3"}},
+		{name: "principal and method", filter: Filter{Principal: "bob", Method: play}, want: []string{"5"}},
+		{name: "no match", filter: Filter{Principal: "carol"}},
+		{name: "limit keeps the latest", filter: Filter{Limit: 2}, want: []string{"4", "5"}},
+		{name: "limit over the matches", filter: Filter{Method: deleteAudio, Limit: 10}, want: []string{"2", "3"}},
+		{name: "limit of filtered", filter: Filter{Method: play, Limit: 2}, want: []string{"4", "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(events); !slices.Equal(got, tt.want) {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLog_QuerySkipsBrokenLines(t *testing.T) {
+	l := newTestLog(t, 0, 0)
+	recordN(t, l, 1)
+	// a line broken by a crash while writing
+	if _, err := l.file.WriteString("{\"id\": \"broken\n"); err != nil {
+		t.Fatal(err)
+	}
+	l.size += int64(len("{\"id\": \"broken\n"))
+	if err := l.Record(Event{Id: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(events), []string{"e0", "e1"}; !slices.Equal(got, want) {
+		t.Errorf("Query() = %v, want %v", got, want)
+	}
+}
+
+func TestLog_QueryReadsSnapshot(t *testing.T) {
+	l := newTestLog(t, 0, 0)
+	recordN(t, l, 2)
+
+	files, err := l.snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer files[0].file.Close()
+	// events recorded after the snapshot are not read
+	if err := l.Record(Event{Id: "late"}); err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	if err := readEvents(files[0].r, Filter{}, &events); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ids(events), []string{"e0", "e1"}; !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const maxRequestSummaryLength = 512
+
+// UnaryServerInterceptor records calls of methods for which audited returns true.
+// It must be chained after the authentication interceptor and before
+// the interceptors that may reject the call, so that rejected attempts are recorded too.
+func UnaryServerInterceptor(l *Log, audited func(fullMethod string) bool, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !audited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		e := Event{
+			Time:    time.Now().UTC(),
+			Method:  info.FullMethod,
+			Request: summary(req),
+		}
+		if p := auth.FromContext(ctx); p != nil {
+			e.Principal = p.Subject
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			e.Peer = p.Addr.String()
+		}
+
+		resp, err := handler(ctx, req)
+
+		st := status.Convert(err)
+		e.Code = st.Code().String()
+		if err != nil {
+			e.Error = st.Message()
+		}
+		if rerr := l.Record(e); rerr != nil {
+			logger.ErrorContext(ctx, "record audit event error", slog.Any("error", rerr))
+		}
+		return resp, err
+	}
+}
+
+func summary(req any) string {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	if len(b) <= maxRequestSummaryLength {
+		return string(b)
+	}
+	b = b[:maxRequestSummaryLength]
+	for len(b) > 0 && !utf8.Valid(b) {
+		b = b[:len(b)-1]
+	}
+	return string(b) + "..."
+}
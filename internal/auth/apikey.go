@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+const MethodAPIKey = "api_key"
+
+type apiKeyEntry struct {
+	Key     string   `json:"key"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// APIKeyAuthenticator authenticates callers by static API keys.
+type APIKeyAuthenticator struct {
+	keys map[[sha256.Size]byte]apiKeyEntry
+}
+
+// NewAPIKeyAuthenticator loads API keys from a JSON file with an array
+// of objects with key, subject and roles fields.
+func NewAPIKeyAuthenticator(filename string) (*APIKeyAuthenticator, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read api keys file error: %w", err)
+	}
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse api keys file error: %w", err)
+	}
+
+	a := &APIKeyAuthenticator{keys: make(map[[sha256.Size]byte]apiKeyEntry, len(entries))}
+	for i, e := range entries {
+		if e.Key == "" || e.Subject == "" {
+			return nil, fmt.Errorf("api key #%d: key and subject must not be empty", i)
+		}
+		a.keys[sha256.Sum256([]byte(e.Key))] = e
+	}
+	return a, nil
+}
+
+func (a *APIKeyAuthenticator) Authenticate(_ context.Context, creds Credentials) (*Principal, error) {
+	if creds.APIKey == "" {
+		return nil, ErrNoCredentials
+	}
+	// keys are looked up by hash, the final comparison is constant-time
+	e, ok := a.keys[sha256.Sum256([]byte(creds.APIKey))]
+	if !ok || subtle.ConstantTimeCompare([]byte(e.Key), []byte(creds.APIKey)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{
+		Subject: e.Subject,
+		Roles:   e.Roles,
+		Method:  MethodAPIKey,
+	}, nil
+}
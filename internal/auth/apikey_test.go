@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeFile writes data to a file in a temporary directory and returns its name.
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	a, err := NewAPIKeyAuthenticator(writeFile(t, "keys.json", []byte(
+		`[{"key": "k1", "subject": "alice", "roles": ["admin"]}, {"key": "k2", "subject": "bob"}]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		subject string
+		roles   []string
+		err     error
+	}{
+		{name: "valid key with roles", key: "k1", subject: "alice", roles: []string{"admin"}},
+		{name: "valid key without roles", key: "k2", subject: "bob"},
+		{name: "unknown key", key: "k3", err: ErrInvalidCredentials},
+		{name: "key prefix", key: "k", err: ErrInvalidCredentials},
+		{name: "no key", err: ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(context.Background(), Credentials{APIKey: tt.key})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Subject != tt.subject || !slices.Equal(p.Roles, tt.roles) || p.Method != MethodAPIKey {
+				t.Errorf("got principal %+v, want subject %s and roles %v", p, tt.subject, tt.roles)
+			}
+		})
+	}
+}
+
+func TestNewAPIKeyAuthenticator_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not json", data: "k1"},
+		{name: "empty key", data: `[{"key": "", "subject": "alice"}]`},
+		{name: "empty subject", data: `[{"key": "k1"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAPIKeyAuthenticator(writeFile(t, "keys.json", []byte(tt.data))); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+
+	if _, err := NewAPIKeyAuthenticator(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("got no error for a missing file")
+	}
+}
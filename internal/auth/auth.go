@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AuthorizationHeader is the metadata key of a bearer token.
+	AuthorizationHeader = "authorization"
+	// APIKeyHeader is the metadata key of an API key.
+	APIKeyHeader = "x-api-key"
+
+	bearerPrefix = "bearer "
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject identifies the caller, e.g. the sub claim of a token.
+	Subject string
+	// Roles are the roles granted to the caller by its credentials.
+	Roles []string
+	// Method is the authentication method the caller used.
+	Method string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx with the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal from ctx or nil if the caller is not authenticated.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Subject returns the subject of the principal from ctx
+// or empty string if the caller is not authenticated.
+func Subject(ctx context.Context) string {
+	if p := FromContext(ctx); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+// Credentials are credentials extracted from request metadata.
+type Credentials struct {
+	BearerToken string
+	APIKey      string
+}
+
+// Authenticator verifies credentials. It returns ErrNoCredentials
+// if credentials of its kind are not present.
+type Authenticator interface {
+	Authenticate(ctx context.Context, creds Credentials) (*Principal, error)
+}
+
+// publicMethods do not require authentication.
+var publicMethods = []string{
+	"/grpc.health.v1.Health/",
+}
+
+func isPublic(fullMethod string) bool {
+	for _, m := range publicMethods {
+		if strings.HasPrefix(fullMethod, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func credentialsFromContext(ctx context.Context) Credentials {
+	var creds Credentials
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return creds
+	}
+	if vals := md.Get(AuthorizationHeader); len(vals) > 0 {
+		if len(vals[0]) > len(bearerPrefix) && strings.EqualFold(vals[0][:len(bearerPrefix)], bearerPrefix) {
+			creds.BearerToken = vals[0][len(bearerPrefix):]
+		}
+	}
+	if vals := md.Get(APIKeyHeader); len(vals) > 0 {
+		creds.APIKey = vals[0]
+	}
+	return creds
+}
+
+// authenticate tries authenticators in order and returns
+// the context with the principal of the first one that succeeds.
+func authenticate(ctx context.Context, fullMethod string, authns []Authenticator) (context.Context, error) {
+	if isPublic(fullMethod) {
+		return ctx, nil
+	}
+
+	creds := credentialsFromContext(ctx)
+	for _, a := range authns {
+		p, err := a.Authenticate(ctx, creds)
+		switch {
+		case err == nil:
+			return WithPrincipal(ctx, p), nil
+		case errors.Is(err, ErrNoCredentials):
+			continue
+		default:
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, ErrNoCredentials.Error())
+}
+
+// UnaryServerInterceptor rejects unauthenticated calls
+// and puts the principal of authenticated ones to the context.
+func UnaryServerInterceptor(authns ...Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, info.FullMethod, authns)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the stream counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(authns ...Authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), info.FullMethod, authns)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
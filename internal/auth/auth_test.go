@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a, err := NewAPIKeyAuthenticator(writeFile(t, "keys.json", []byte(`[{"key": "k1", "subject": "alice"}]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryServerInterceptor(a)
+
+	tests := []struct {
+		name    string
+		method  string
+		md      metadata.MD
+		subject string
+		code    codes.Code
+	}{
+		{name: "api key", method: "/grpcapi.PlayerService/Play", md: metadata.Pairs(APIKeyHeader, "k1"), subject: "alice"},
+		{name: "invalid api key", method: "/grpcapi.PlayerService/Play", md: metadata.Pairs(APIKeyHeader, "k2"), code: codes.Unauthenticated},
+		{name: "no credentials", method: "/grpcapi.PlayerService/Play", code: codes.Unauthenticated},
+		// a bearer token is not checked by the API key authenticator
+		{name: "unsupported credentials", method: "/grpcapi.PlayerService/Play", md: metadata.Pairs(AuthorizationHeader, "Bearer token"), code: codes.Unauthenticated},
+		{name: "public method", method: "/grpc.health.v1.Health/Check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			var subject string
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ any) (any, error) {
+				subject = Subject(ctx)
+				return nil, nil
+			})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("got code %s, want %s", code, tt.code)
+			}
+			if subject != tt.subject {
+				t.Errorf("got subject %q, want %q", subject, tt.subject)
+			}
+		})
+	}
+}
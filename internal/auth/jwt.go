@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const MethodJWT = "jwt"
+
+type jwtConfig interface {
+	// JWTSecretFile returns the file with HS256 secret, empty value disables HS256.
+	JWTSecretFile() string
+	// JWTPublicKeyFile returns the PEM file with RS256 public key, empty value disables RS256.
+	JWTPublicKeyFile() string
+	// JWTAudience returns the required audience, empty value disables the check.
+	JWTAudience() string
+	// JWTIssuer returns the required issuer, empty value disables the check.
+	JWTIssuer() string
+}
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// JWTAuthenticator authenticates callers by HS256 or RS256 signed bearer tokens.
+// Tokens must have the sub and exp claims.
+type JWTAuthenticator struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	parser    *jwt.Parser
+}
+
+func NewJWTAuthenticator(cfg jwtConfig) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{}
+
+	var methods []string
+	if cfg.JWTSecretFile() != "" {
+		secret, err := os.ReadFile(cfg.JWTSecretFile())
+		if err != nil {
+			return nil, fmt.Errorf("read jwt secret file error: %w", err)
+		}
+		if len(secret) == 0 {
+			return nil, errors.New("jwt secret must not be empty")
+		}
+		a.secret = secret
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWTPublicKeyFile() != "" {
+		data, err := os.ReadFile(cfg.JWTPublicKeyFile())
+		if err != nil {
+			return nil, fmt.Errorf("read jwt public key file error: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt public key error: %w", err)
+		}
+		a.publicKey = key
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("neither jwt secret nor public key is set")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.JWTAudience() != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience()))
+	}
+	if cfg.JWTIssuer() != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer()))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(_ context.Context, creds Credentials) (*Principal, error) {
+	if creds.BearerToken == "" {
+		return nil, ErrNoCredentials
+	}
+
+	var claims jwtClaims
+	if _, err := a.parser.ParseWithClaims(creds.BearerToken, &claims, a.key); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &Principal{
+		Subject: claims.Subject,
+		Roles:   claims.Roles,
+		Method:  MethodJWT,
+	}, nil
+}
+
+func (a *JWTAuthenticator) key(t *jwt.Token) (any, error) {
+	switch t.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return a.secret, nil
+	case *jwt.SigningMethodRSA:
+		return a.publicKey, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+	}
+}
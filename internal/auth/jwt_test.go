@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testJWTConfig struct {
+	secretFile, publicKeyFile, audience, issuer string
+}
+
+func (c testJWTConfig) JWTSecretFile() string    { return c.secretFile }
+func (c testJWTConfig) JWTPublicKeyFile() string { return c.publicKeyFile }
+func (c testJWTConfig) JWTAudience() string      { return c.audience }
+func (c testJWTConfig) JWTIssuer() string        { return c.issuer }
+
+var testSecret = []byte("test secret")
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwtClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// claims returns valid claims of alice expiring in d.
+func claims(d time.Duration) jwtClaims {
+	return jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alice",
+			Audience:  jwt.ClaimStrings{"player"},
+			Issuer:    "issuer",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(d)),
+		},
+		Roles: []string{"admin"},
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewJWTAuthenticator(testJWTConfig{
+		secretFile:    writeFile(t, "secret", testSecret),
+		publicKeyFile: writeFile(t, "key.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		audience:      "player",
+		issuer:        "issuer",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		err   error
+	}{
+		{name: "HS256", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, testSecret, claims(time.Hour))
+		}},
+		{name: "RS256", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, rsaKey, claims(time.Hour))
+		}},
+		{name: "no token", token: func(*testing.T) string { return "" }, err: ErrNoCredentials},
+		{name: "malformed", token: func(*testing.T) string { return "not a token" }, err: jwt.ErrTokenMalformed},
+		{name: "expired", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, testSecret, claims(-time.Minute))
+		}, err: jwt.ErrTokenExpired},
+		{name: "no expiry", token: func(t *testing.T) string {
+			c := claims(time.Hour)
+			c.ExpiresAt = nil
+			return sign(t, jwt.SigningMethodHS256, testSecret, c)
+		}, err: jwt.ErrTokenRequiredClaimMissing},
+		{name: "HS256 bad signature", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS256, []byte("other secret"), claims(time.Hour))
+		}, err: jwt.ErrTokenSignatureInvalid},
+		{name: "RS256 bad signature", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodRS256, otherRSAKey, claims(time.Hour))
+		}, err: jwt.ErrTokenSignatureInvalid},
+		{name: "unexpected method", token: func(t *testing.T) string {
+			return sign(t, jwt.SigningMethodHS512, testSecret, claims(time.Hour))
+		}, err: jwt.ErrTokenSignatureInvalid},
+		{name: "wrong audience", token: func(t *testing.T) string {
+			c := claims(time.Hour)
+			c.Audience = jwt.ClaimStrings{"other"}
+			return sign(t, jwt.SigningMethodHS256, testSecret, c)
+		}, err: jwt.ErrTokenInvalidAudience},
+		{name: "wrong issuer", token: func(t *testing.T) string {
+			c := claims(time.Hour)
+			c.Issuer = "other"
+			return sign(t, jwt.SigningMethodHS256, testSecret, c)
+		}, err: jwt.ErrTokenInvalidIssuer},
+		{name: "no subject", token: func(t *testing.T) string {
+			c := claims(time.Hour)
+			c.Subject = ""
+			return sign(t, jwt.SigningMethodHS256, testSecret, c)
+		}, err: ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(context.Background(), Credentials{BearerToken: tt.token(t)})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				if tt.err != ErrNoCredentials && !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("got error %v, want it to be %v", err, ErrInvalidCredentials)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Subject != "alice" || !slices.Equal(p.Roles, []string{"admin"}) || p.Method != MethodJWT {
+				t.Errorf("got principal %+v", p)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticator_DisabledMethod(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a token signed by RS256 must be rejected when only HS256 is configured
+	a, err := NewJWTAuthenticator(testJWTConfig{secretFile: writeFile(t, "secret", testSecret)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jwt.SigningMethodRS256, rsaKey, claims(time.Hour))
+	if _, err := a.Authenticate(context.Background(), Credentials{BearerToken: token}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestNewJWTAuthenticator_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(t *testing.T) testJWTConfig
+	}{
+		{name: "nothing configured", cfg: func(*testing.T) testJWTConfig { return testJWTConfig{} }},
+		{name: "empty secret", cfg: func(t *testing.T) testJWTConfig {
+			return testJWTConfig{secretFile: writeFile(t, "secret", nil)}
+		}},
+		{name: "invalid public key", cfg: func(t *testing.T) testJWTConfig {
+			return testJWTConfig{publicKeyFile: writeFile(t, "key.pem", []byte("not a key"))}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewJWTAuthenticator(tt.cfg(t)); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const MethodTLS = "tls"
+
+// TLSAuthenticator authenticates callers by verified client certificates,
+// the subject of the leaf certificate becomes the principal subject.
+type TLSAuthenticator struct{}
+
+func (TLSAuthenticator) Authenticate(ctx context.Context, _ Credentials) (*Principal, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+
+	subject := tlsInfo.State.VerifiedChains[0][0].Subject
+	name := subject.CommonName
+	if name == "" {
+		name = subject.String()
+	}
+	return &Principal{
+		Subject: name,
+		Method:  MethodTLS,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// peerContext returns a context of a call over a connection with the TLS state.
+func peerContext(state tls.ConnectionState) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func verified(subject pkix.Name) tls.ConnectionState {
+	leaf := &x509.Certificate{Subject: subject}
+	ca := &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}}
+	return tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   [][]*x509.Certificate{{leaf, ca}},
+	}
+}
+
+func TestTLSAuthenticator(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		subject string
+		err     error
+	}{
+		{name: "common name", ctx: peerContext(verified(pkix.Name{CommonName: "alice", Organization: []string{"org"}})), subject: "alice"},
+		{name: "no common name", ctx: peerContext(verified(pkix.Name{Organization: []string{"org"}})), subject: "O=org"},
+		{name: "unverified certificate", ctx: peerContext(tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "alice"}}},
+		}), err: ErrNoCredentials},
+		{name: "no TLS", ctx: peer.NewContext(context.Background(), &peer.Peer{}), err: ErrNoCredentials},
+		{name: "no peer", ctx: context.Background(), err: ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := TLSAuthenticator{}.Authenticate(tt.ctx, Credentials{})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Subject != tt.subject || p.Method != MethodTLS {
+				t.Errorf("got principal %+v, want subject %q", p, tt.subject)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"github.com/Karzoug/gocloudcamp/internal/filewatch"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ProtectedPrefix is the prefix of methods the authorizer checks,
+// calls of other services are passed through.
+const ProtectedPrefix = "/grpcapi.PlayerService/"
+
+const reloadInterval = 5 * time.Second
+
+// policy maps roles to permitted methods and subjects to roles.
+//
+//	{
+//	  "roles": {
+//	    "listener": ["/grpcapi.PlayerService/ListAudio", "/grpcapi.PlayerService/ReadAudio"],
+//	    "admin": ["/grpcapi.PlayerService/*"]
+//	  },
+//	  "bindings": {"alice": ["listener"]}
+//	}
+//
+// A permission ending with * permits all methods with the preceding prefix.
+// Roles of a principal are the roles from its credentials and from bindings.
+type policy struct {
+	Roles    map[string][]string `json:"roles"`
+	Bindings map[string][]string `json:"bindings"`
+}
+
+func (p *policy) validate() error {
+	for subject, roles := range p.Bindings {
+		for _, r := range roles {
+			if _, ok := p.Roles[r]; !ok {
+				return fmt.Errorf("subject %s is bound to unknown role %s", subject, r)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *policy) allowed(principal *auth.Principal, fullMethod string) bool {
+	if principal == nil {
+		return false
+	}
+	for _, roles := range [][]string{principal.Roles, p.Bindings[principal.Subject]} {
+		for _, r := range roles {
+			for _, perm := range p.Roles[r] {
+				if perm == fullMethod ||
+					strings.HasSuffix(perm, "*") && strings.HasPrefix(fullMethod, strings.TrimSuffix(perm, "*")) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Authorizer checks that callers have a role permitting the called method.
+type Authorizer struct {
+	filename string
+	policy   atomic.Pointer[policy]
+	logger   *slog.Logger
+}
+
+// New creates an authorizer with the policy loaded from the JSON file.
+func New(filename string, logger *slog.Logger) (*Authorizer, error) {
+	a := &Authorizer{
+		filename: filename,
+		logger:   logger,
+	}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload loads the policy file again. The current policy
+// is kept if the file cannot be loaded.
+func (a *Authorizer) Reload() error {
+	data, err := os.ReadFile(a.filename)
+	if err != nil {
+		return fmt.Errorf("read policy file error: %w", err)
+	}
+	var p policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("parse policy file error: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	a.policy.Store(&p)
+	return nil
+}
+
+// Watch reloads the policy every time the policy file changes, until ctx is done.
+func (a *Authorizer) Watch(ctx context.Context) {
+	filewatch.Watch(ctx, reloadInterval, func() {
+		if err := a.Reload(); err != nil {
+			a.logger.Error("reload policy error", slog.Any("error", err))
+			return
+		}
+		a.logger.Info("policy reloaded", slog.String("file", a.filename))
+	}, a.filename)
+}
+
+func (a *Authorizer) authorize(ctx context.Context, fullMethod string) error {
+	if !strings.HasPrefix(fullMethod, ProtectedPrefix) {
+		return nil
+	}
+	if !a.policy.Load().allowed(auth.FromContext(ctx), fullMethod) {
+		return status.Errorf(codes.PermissionDenied, "permission denied for %s", fullMethod)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor rejects calls not permitted by the policy.
+// It must be chained after the authentication interceptor.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the stream counterpart of UnaryServerInterceptor.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,149 @@
+package authz
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testPolicy = `{
+  "roles": {
+    "listener": ["/grpcapi.PlayerService/ListAudio", "/grpcapi.PlayerService/ReadAudio"],
+    "dj": ["/grpcapi.PlayerService/Play", "/grpcapi.PlayerService/Pause"],
+    "admin": ["/grpcapi.PlayerService/*"],
+    "auditor": ["/grpcapi.PlayerService/ListAudit*"],
+    "empty": []
+  },
+  "bindings": {"alice": ["listener"], "bob": ["listener", "dj"], "root": ["admin"]}
+}`
+
+func writePolicy(t *testing.T, filename, policy string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestAuthorizer(t *testing.T, policy string) (*Authorizer, string) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, filename, policy)
+	a, err := New(filename, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, filename
+}
+
+// call returns the code of the call of the method by the principal through the interceptor.
+func call(a *Authorizer, principal *auth.Principal, method string) codes.Code {
+	ctx := context.Background()
+	if principal != nil {
+		ctx = auth.WithPrincipal(ctx, principal)
+	}
+	_, err := a.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+		return nil, nil
+	})
+	return status.Code(err)
+}
+
+func TestAuthorizer(t *testing.T) {
+	a, _ := newTestAuthorizer(t, testPolicy)
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		want      codes.Code
+	}{
+		{name: "bound role", principal: &auth.Principal{Subject: "alice"}, method: "/grpcapi.PlayerService/ListAudio", want: codes.OK},
+		{name: "not permitted by bound role", principal: &auth.Principal{Subject: "alice"}, method: "/grpcapi.PlayerService/Play", want: codes.PermissionDenied},
+		{name: "second bound role", principal: &auth.Principal{Subject: "bob"}, method: "/grpcapi.PlayerService/Pause", want: codes.OK},
+		{name: "credential role", principal: &auth.Principal{Subject: "carol", Roles: []string{"dj"}}, method: "/grpcapi.PlayerService/Play", want: codes.OK},
+		{
+			name:      "credential and bound roles",
+			principal: &auth.Principal{Subject: "alice", Roles: []string{"dj"}},
+			method:    "/grpcapi.PlayerService/ReadAudio",
+			want:      codes.OK,
+		},
+		{name: "unknown credential role", principal: &auth.Principal{Subject: "carol", Roles: []string{"owner"}}, method: "/grpcapi.PlayerService/Play", want: codes.PermissionDenied},
+		{name: "role without permissions", principal: &auth.Principal{Subject: "carol", Roles: []string{"empty"}}, method: "/grpcapi.PlayerService/Play", want: codes.PermissionDenied},
+		{name: "wildcard", principal: &auth.Principal{Subject: "root"}, method: "/grpcapi.PlayerService/DeleteAudio", want: codes.OK},
+		{name: "wildcard prefix", principal: &auth.Principal{Roles: []string{"auditor"}}, method: "/grpcapi.PlayerService/ListAuditEvents", want: codes.OK},
+		{name: "not matching wildcard prefix", principal: &auth.Principal{Roles: []string{"auditor"}}, method: "/grpcapi.PlayerService/ListAudio", want: codes.PermissionDenied},
+		{name: "unbound principal", principal: &auth.Principal{Subject: "mallory"}, method: "/grpcapi.PlayerService/ListAudio", want: codes.PermissionDenied},
+		{name: "no principal", method: "/grpcapi.PlayerService/ListAudio", want: codes.PermissionDenied},
+		{name: "unprotected method", method: "/grpc.health.v1.Health/Check", want: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := call(a, tt.principal, tt.method); got != tt.want {
+				t.Errorf("code = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_InvalidPolicy(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "policy.json"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err == nil {
+		t.Error("New() of missing file error = nil")
+	}
+
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, filename, `{"roles": {"listener": []}, "bindings": {"alice": ["admin"]}}`)
+	if _, err := New(filename, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Error("New() of policy with unknown role error = nil")
+	}
+}
+
+func TestAuthorizer_Reload(t *testing.T) {
+	alice := &auth.Principal{Subject: "alice"}
+	const play = "/grpcapi.PlayerService/Play"
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+		// want is the code of Play by alice after the reload
+		want codes.Code
+	}{
+		{name: "unknown role", policy: `{"roles": {"dj": ["` + play + `"]}, "bindings": {"alice": ["dj", "admin"]}}`, wantErr: true, want: codes.PermissionDenied},
+		{name: "broken JSON", policy: `{"roles": `, wantErr: true, want: codes.PermissionDenied},
+		{name: "missing file", wantErr: true, want: codes.PermissionDenied},
+		{name: "valid", policy: `{"roles": {"dj": ["` + play + `"]}, "bindings": {"alice": ["dj"]}}`, want: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, filename := newTestAuthorizer(t, testPolicy)
+			if tt.policy == "" {
+				if err := os.Remove(filename); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				writePolicy(t, filename, tt.policy)
+			}
+
+			if err := a.Reload(); (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := call(a, alice, play); got != tt.want {
+				t.Errorf("Play code = %s, want %s", got, tt.want)
+			}
+			// the previous policy is kept after a failed reload
+			wantList := codes.OK
+			if !tt.wantErr {
+				wantList = codes.PermissionDenied
+			}
+			if got := call(a, alice, "/grpcapi.PlayerService/ListAudio"); got != wantList {
+				t.Errorf("ListAudio code = %s, want %s", got, wantList)
+			}
+		})
+	}
+}
@@ -3,26 +3,87 @@ package config
 import (
 	"errors"
 	"flag"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	port      int
-	storeFile string
-	restore   bool
+	port          int
+	storeFile     string
+	restore       bool
+	reflection    bool
+	metricsAddr   string
+	gatewayAddr   string
+	grpcWebAddr   string
+	corsOrigins   string
+	traceExporter string
+	otlpEndpoint  string
+	logLevel      string
+	logFormat     string
+	logUserText   bool
+
+	apiKeysFile      string
+	jwtSecretFile    string
+	jwtPublicKeyFile string
+	jwtAudience      string
+	jwtIssuer        string
+	policyFile       string
+
+	tlsCertFile     string
+	tlsKeyFile      string
+	tlsClientCAFile string
+
+	rateLimitsFile string
+	coalesceWindow time.Duration
+	eventsBuffer   int
+	trashRetention time.Duration
+	historyDepth   int
+
+	auditFile       string
+	auditMaxSizeMB  int
+	auditMaxBackups int
 }
 
 const (
-	defaultPort      = 50052
-	defaultStoreFile = "/tmp/gocloud_player.json"
-	defaultRestore   = true
+	defaultPort          = 50052
+	defaultStoreFile     = "/tmp/gocloud_player.json"
+	defaultRestore       = true
+	defaultReflection    = false
+	defaultMetricsAddr   = ""
+	defaultGatewayAddr   = ""
+	defaultTraceExporter = ""
+	defaultOTLPEndpoint  = "localhost:4317"
+	defaultLogLevel      = "info"
+	defaultLogFormat     = "text"
+
+	defaultEventsBuffer   = 1024
+	defaultTrashRetention = 24 * time.Hour
+	defaultHistoryDepth   = 100
+
+	defaultAuditMaxSizeMB  = 10
+	defaultAuditMaxBackups = 5
 )
 
 // New creates Config with default values.
 func New() *Config {
 	return &Config{
-		port:      defaultPort,
-		storeFile: defaultStoreFile,
-		restore:   defaultRestore,
+		port:          defaultPort,
+		storeFile:     defaultStoreFile,
+		restore:       defaultRestore,
+		reflection:    defaultReflection,
+		metricsAddr:   defaultMetricsAddr,
+		gatewayAddr:   defaultGatewayAddr,
+		traceExporter: defaultTraceExporter,
+		otlpEndpoint:  defaultOTLPEndpoint,
+		logLevel:      defaultLogLevel,
+		logFormat:     defaultLogFormat,
+
+		eventsBuffer:   defaultEventsBuffer,
+		trashRetention: defaultTrashRetention,
+		historyDepth:   defaultHistoryDepth,
+
+		auditMaxSizeMB:  defaultAuditMaxSizeMB,
+		auditMaxBackups: defaultAuditMaxBackups,
 	}
 }
 
@@ -38,6 +99,170 @@ func (c Config) Restore() bool {
 	return c.restore
 }
 
+// Reflection reports whether gRPC server reflection should be enabled.
+func (c Config) Reflection() bool {
+	return c.reflection
+}
+
+// MetricsAddr returns the address of the HTTP server exposing /metrics,
+// empty value disables it.
+func (c Config) MetricsAddr() string {
+	return c.metricsAddr
+}
+
+// GatewayAddr returns the address of the REST/JSON gateway, which also
+// serves the WebSocket events and the OpenAPI document, empty value disables it.
+func (c Config) GatewayAddr() string {
+	return c.gatewayAddr
+}
+
+// GRPCWebAddr returns the address of the gRPC-Web server, empty value disables it.
+func (c Config) GRPCWebAddr() string {
+	return c.grpcWebAddr
+}
+
+// CORSOrigins returns the origins from which browsers may call the gateway
+// and gRPC-Web server, "*" means any origin.
+func (c Config) CORSOrigins() []string {
+	var origins []string
+	for _, o := range strings.Split(c.corsOrigins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+// TraceExporter returns the name of the trace exporter: "stdout", "otlp"
+// or empty value if tracing is disabled.
+func (c Config) TraceExporter() string {
+	return c.traceExporter
+}
+
+// OTLPEndpoint returns the address of the OTLP gRPC trace collector.
+func (c Config) OTLPEndpoint() string {
+	return c.otlpEndpoint
+}
+
+// LogLevel returns the minimal level of log records: debug, info, warn or error.
+func (c Config) LogLevel() string {
+	return c.logLevel
+}
+
+// LogFormat returns the format of log records: text or json.
+func (c Config) LogFormat() string {
+	return c.logFormat
+}
+
+// LogUserText reports whether text provided by users, e.g. audio names,
+// is logged as is rather than redacted.
+func (c Config) LogUserText() bool {
+	return c.logUserText
+}
+
+// APIKeysFile returns the JSON file with API keys, empty value disables API keys authentication.
+func (c Config) APIKeysFile() string {
+	return c.apiKeysFile
+}
+
+func (c Config) JWTSecretFile() string {
+	return c.jwtSecretFile
+}
+
+func (c Config) JWTPublicKeyFile() string {
+	return c.jwtPublicKeyFile
+}
+
+func (c Config) JWTAudience() string {
+	return c.jwtAudience
+}
+
+func (c Config) JWTIssuer() string {
+	return c.jwtIssuer
+}
+
+// PolicyFile returns the JSON file with the authorization policy,
+// empty value disables authorization.
+func (c Config) PolicyFile() string {
+	return c.policyFile
+}
+
+// IsAuthEnabled reports whether any authentication method is configured.
+func (c Config) IsAuthEnabled() bool {
+	return c.apiKeysFile != "" || c.IsJWTEnabled() || c.IsMutualTLSEnabled()
+}
+
+func (c Config) TLSCertFile() string {
+	return c.tlsCertFile
+}
+
+func (c Config) TLSKeyFile() string {
+	return c.tlsKeyFile
+}
+
+func (c Config) TLSClientCAFile() string {
+	return c.tlsClientCAFile
+}
+
+// IsTLSEnabled reports whether the server should serve TLS.
+func (c Config) IsTLSEnabled() bool {
+	return c.tlsCertFile != "" && c.tlsKeyFile != ""
+}
+
+// IsMutualTLSEnabled reports whether the server should verify client certificates.
+func (c Config) IsMutualTLSEnabled() bool {
+	return c.IsTLSEnabled() && c.tlsClientCAFile != ""
+}
+
+// IsJWTEnabled reports whether JWT authentication is configured.
+func (c Config) IsJWTEnabled() bool {
+	return c.jwtSecretFile != "" || c.jwtPublicKeyFile != ""
+}
+
+// RateLimitsFile returns the JSON file with rate limits, empty value disables rate limiting.
+func (c Config) RateLimitsFile() string {
+	return c.rateLimitsFile
+}
+
+// CoalesceWindow returns the time Next and Prev commands are collected
+// to be handled at once, zero disables coalescing.
+func (c Config) CoalesceWindow() time.Duration {
+	return c.coalesceWindow
+}
+
+// EventsBuffer returns the number of the latest player events kept
+// for subscribers resuming after reconnect.
+func (c Config) EventsBuffer() int {
+	return c.eventsBuffer
+}
+
+// TrashRetention returns the time deleted audios are kept in the trash,
+// zero disables the trash.
+func (c Config) TrashRetention() time.Duration {
+	return c.trashRetention
+}
+
+// HistoryDepth returns the number of the latest playlist changes
+// that can be undone, zero disables the history.
+func (c Config) HistoryDepth() int {
+	return c.historyDepth
+}
+
+// AuditFile returns the file of the audit log, empty value disables auditing.
+func (c Config) AuditFile() string {
+	return c.auditFile
+}
+
+// AuditMaxSize returns the size in bytes after which the audit log is rotated.
+func (c Config) AuditMaxSize() int64 {
+	return int64(c.auditMaxSizeMB) << 20
+}
+
+// AuditMaxBackups returns the number of rotated audit log files to keep.
+func (c Config) AuditMaxBackups() int {
+	return c.auditMaxBackups
+}
+
 func (с Config) IsStoreInMemory() bool {
 	return с.storeFile == ""
 }
@@ -51,7 +276,53 @@ func (c *Config) Load() error {
 	flag.IntVar(&c.port, "p", defaultPort, "server port")
 	flag.StringVar(&c.storeFile, "f", defaultStoreFile, "filename to save/load playlist")
 	flag.BoolVar(&c.restore, "r", defaultRestore, "whether to load saved data at startup")
+	flag.BoolVar(&c.reflection, "reflection", defaultReflection, "whether to enable gRPC server reflection")
+	flag.StringVar(&c.metricsAddr, "metrics", defaultMetricsAddr, "address of the metrics HTTP server, e.g. :9090 (empty value disables it)")
+	flag.StringVar(&c.gatewayAddr, "gateway", defaultGatewayAddr, "address of the REST/JSON gateway serving also WebSocket events and OpenAPI, e.g. :8080 (empty value disables it)")
+	flag.StringVar(&c.grpcWebAddr, "grpc-web", "", "address of the gRPC-Web server (empty value disables it)")
+	flag.StringVar(&c.corsOrigins, "cors-origins", "", "comma-separated origins allowed to call the gateway and gRPC-Web server from browsers, * allows any origin without browser credentials")
+	flag.StringVar(&c.traceExporter, "trace", defaultTraceExporter, "trace exporter: stdout, otlp (empty value disables tracing)")
+	flag.StringVar(&c.otlpEndpoint, "otlp-endpoint", defaultOTLPEndpoint, "address of the OTLP gRPC trace collector")
+	flag.StringVar(&c.logLevel, "log-level", defaultLogLevel, "log level: debug, info, warn, error")
+	flag.StringVar(&c.logFormat, "log-format", defaultLogFormat, "log format: text, json")
+	flag.BoolVar(&c.logUserText, "log-user-text", false, "whether to log user-provided text such as audio names as is (it is redacted at every level by default)")
+	flag.StringVar(&c.apiKeysFile, "api-keys", "", "JSON file with API keys (empty value disables API keys authentication)")
+	flag.StringVar(&c.jwtSecretFile, "jwt-secret", "", "file with HS256 secret of JWT")
+	flag.StringVar(&c.jwtPublicKeyFile, "jwt-public-key", "", "PEM file with RS256 public key of JWT")
+	flag.StringVar(&c.jwtAudience, "jwt-audience", "", "required audience of JWT")
+	flag.StringVar(&c.jwtIssuer, "jwt-issuer", "", "required issuer of JWT")
+	flag.StringVar(&c.policyFile, "policy", "", "JSON file with authorization policy (empty value disables authorization)")
+	flag.StringVar(&c.tlsCertFile, "tls-cert", "", "PEM file with server TLS certificate (empty value disables TLS)")
+	flag.StringVar(&c.tlsKeyFile, "tls-key", "", "PEM file with server TLS private key")
+	flag.StringVar(&c.tlsClientCAFile, "tls-client-ca", "", "PEM file with CA bundle to verify client certificates (empty value disables mutual TLS)")
+	flag.StringVar(&c.rateLimitsFile, "rate-limits", "", "JSON file with per-client rate limits (empty value disables rate limiting)")
+	flag.DurationVar(&c.coalesceWindow, "coalesce-window", 0, "time to collect repeated Next/Prev commands to skip at once (0 disables coalescing)")
+	flag.IntVar(&c.eventsBuffer, "events-buffer", defaultEventsBuffer, "number of the latest player events kept for subscribers resuming after reconnect")
+	flag.DurationVar(&c.trashRetention, "trash-retention", defaultTrashRetention, "time deleted audios are kept in the trash to be restored (0 disables the trash)")
+	flag.IntVar(&c.historyDepth, "history-depth", defaultHistoryDepth, "number of the latest playlist changes that can be undone (0 disables undo and redo)")
+	flag.StringVar(&c.auditFile, "audit-file", "", "file of the audit log of mutating calls (empty value disables auditing)")
+	flag.IntVar(&c.auditMaxSizeMB, "audit-max-size", defaultAuditMaxSizeMB, "size in megabytes after which the audit log is rotated")
+	flag.IntVar(&c.auditMaxBackups, "audit-max-backups", defaultAuditMaxBackups, "number of rotated audit log files to keep")
 	flag.Parse()
 
+	if (c.tlsCertFile == "") != (c.tlsKeyFile == "") {
+		return errors.New("both TLS certificate and key must be set")
+	}
+	if c.tlsClientCAFile != "" && !c.IsTLSEnabled() {
+		return errors.New("client CA requires TLS certificate and key")
+	}
+	if c.trashRetention < 0 {
+		return errors.New("trash retention must not be negative")
+	}
+	if c.historyDepth < 0 {
+		return errors.New("history depth must not be negative")
+	}
+	if c.auditMaxSizeMB < 0 || c.auditMaxBackups < 0 {
+		return errors.New("audit log size and backups must not be negative")
+	}
+	if c.corsOrigins != "" && c.gatewayAddr == "" && c.grpcWebAddr == "" {
+		return errors.New("CORS origins require the gateway or gRPC-Web server")
+	}
+
 	return nil
 }
@@ -0,0 +1,222 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+)
+
+// ErrSlowSubscriber is returned when a subscription is dropped because
+// its subscriber does not keep up with published events.
+var ErrSlowSubscriber = errors.New("subscriber is too slow")
+
+// ErrClosed is returned when a subscription is closed because the bus is closed.
+var ErrClosed = errors.New("events bus is closed")
+
+const subscriptionBufferSize = 64
+
+// Type is a type of event.
+type Type uint8
+
+const (
+	StateChanged Type = iota + 1
+	TrackChanged
+	AudioCreated
+	AudioUpdated
+	AudioDeleted
+	AudioMoved
+	AudioRestored
+)
+
+func (t Type) String() string {
+	switch t {
+	case StateChanged:
+		return "state_changed"
+	case TrackChanged:
+		return "track_changed"
+	case AudioCreated:
+		return "audio_created"
+	case AudioUpdated:
+		return "audio_updated"
+	case AudioDeleted:
+		return "audio_deleted"
+	case AudioMoved:
+		return "audio_moved"
+	case AudioRestored:
+		return "audio_restored"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of the player or the playlist.
+type Event struct {
+	// Id increases by one with every published event.
+	Id   uint64
+	Time time.Time
+	Type Type
+	// State is the new state of the player for StateChanged.
+	State string
+	// Audio is the loaded audio for TrackChanged, the created, updated
+	// or restored one for AudioCreated, AudioUpdated and AudioRestored.
+	Audio *models.Audio
+	// AudioId is the id of the deleted audio for AudioDeleted
+	// and of the moved one for AudioMoved.
+	AudioId string
+	// Index is the requested position of the audio in the playlist for AudioMoved,
+	// an index past the end means the end, and the position of the restored audio
+	// for AudioRestored.
+	Index int
+}
+
+// Bus delivers published events to subscribers and keeps the latest
+// of them in a bounded ring, so that subscribers can resume after reconnect.
+type Bus struct {
+	mtx    sync.Mutex
+	ring   []Event
+	start  int
+	size   int
+	lastId uint64
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBus creates a bus keeping up to size latest events.
+func NewBus(size int) *Bus {
+	if size < 1 {
+		size = 1
+	}
+	return &Bus{
+		ring: make([]Event, size),
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the id and the time to the event and delivers it to subscribers.
+// Subscribers that do not keep up are dropped.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.lastId++
+	e.Id = b.lastId
+	e.Time = time.Now().UTC()
+
+	if b.size < len(b.ring) {
+		b.ring[(b.start+b.size)%len(b.ring)] = e
+		b.size++
+	} else {
+		b.ring[b.start] = e
+		b.start = (b.start + 1) % len(b.ring)
+	}
+
+	for s := range b.subs {
+		if !s.wants(e.Type) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			b.drop(s, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Close closes all subscriptions and the ones made later, so that
+// subscribers stop on shutdown. Published events are still kept.
+func (b *Bus) Close() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s, ErrClosed)
+	}
+}
+
+// drop closes the subscription for the reason, b.mtx must be held.
+func (b *Bus) drop(s *Subscription, err error) {
+	delete(b.subs, s)
+	s.err = err
+	close(s.ch)
+}
+
+// Subscribe subscribes to events of the types, all types if there are none.
+// If afterId is not zero, kept events published after it are returned to be
+// delivered before the subscription ones, lost reports that some of them are not kept anymore.
+func (b *Bus) Subscribe(types []Type, afterId uint64) (sub *Subscription, missed []Event, lost bool) {
+	sub = &Subscription{
+		bus: b,
+		ch:  make(chan Event, subscriptionBufferSize),
+	}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if afterId > 0 {
+		// ids start from one on every start of the server,
+		// so an id after the last one is from the previous run
+		lost = afterId > b.lastId || afterId+uint64(b.size) < b.lastId
+		for i := 0; i < b.size; i++ {
+			e := b.ring[(b.start+i)%len(b.ring)]
+			if e.Id > afterId && sub.wants(e.Type) {
+				missed = append(missed, e)
+			}
+		}
+	}
+	if b.closed {
+		sub.err = ErrClosed
+		close(sub.ch)
+		return sub, missed, lost
+	}
+	b.subs[sub] = struct{}{}
+	return sub, missed, lost
+}
+
+// Subscription receives published events.
+type Subscription struct {
+	bus   *Bus
+	types map[Type]bool
+	ch    chan Event
+	err   error
+}
+
+func (s *Subscription) wants(t Type) bool {
+	return s.types == nil || s.types[t]
+}
+
+// Events returns the channel of events. It is closed if the subscription
+// is dropped because the subscriber does not keep up or the bus is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err returns why the channel of events is closed: ErrSlowSubscriber
+// or ErrClosed, nil if it is open or closed by Close.
+func (s *Subscription) Err() error {
+	s.bus.mtx.Lock()
+	defer s.bus.mtx.Unlock()
+	return s.err
+}
+
+// Close unsubscribes from events.
+func (s *Subscription) Close() {
+	s.bus.mtx.Lock()
+	defer s.bus.mtx.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.ch)
+	}
+}
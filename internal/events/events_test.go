@@ -0,0 +1,88 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubscription_Err(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(b *Bus) *Subscription
+		want error
+	}{
+		{
+			name: "open",
+			run: func(b *Bus) *Subscription {
+				sub, _, _ := b.Subscribe(nil, 0)
+				return sub
+			},
+		},
+		{
+			name: "closed by subscriber",
+			run: func(b *Bus) *Subscription {
+				sub, _, _ := b.Subscribe(nil, 0)
+				sub.Close()
+				return sub
+			},
+		},
+		{
+			name: "slow subscriber",
+			run: func(b *Bus) *Subscription {
+				sub, _, _ := b.Subscribe(nil, 0)
+				for i := 0; i <= subscriptionBufferSize; i++ {
+					b.Publish(Event{Type: StateChanged})
+				}
+				return sub
+			},
+			want: ErrSlowSubscriber,
+		},
+		{
+			name: "bus closed",
+			run: func(b *Bus) *Subscription {
+				sub, _, _ := b.Subscribe(nil, 0)
+				b.Close()
+				return sub
+			},
+			want: ErrClosed,
+		},
+		{
+			name: "subscribed after close",
+			run: func(b *Bus) *Subscription {
+				b.Close()
+				sub, _, _ := b.Subscribe(nil, 0)
+				return sub
+			},
+			want: ErrClosed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.run(NewBus(10))
+			if err := sub.Err(); !errors.Is(err, tt.want) {
+				t.Errorf("Err() = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil {
+				return
+			}
+			for range sub.Events() {
+			}
+		})
+	}
+}
+
+func TestBus_CloseKeepsEvents(t *testing.T) {
+	b := NewBus(10)
+	b.Publish(Event{Type: StateChanged})
+	b.Close()
+	b.Publish(Event{Type: TrackChanged})
+
+	_, missed, lost := b.Subscribe(nil, 0)
+	if len(missed) != 0 || lost {
+		t.Fatalf("Subscribe(0) = %v, %v, want no events", missed, lost)
+	}
+	_, missed, lost = b.Subscribe(nil, 1)
+	if len(missed) != 1 || missed[0].Type != TrackChanged || lost {
+		t.Errorf("Subscribe(1) = %v, %v, want the TrackChanged event", missed, lost)
+	}
+}
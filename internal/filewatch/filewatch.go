@@ -0,0 +1,44 @@
+package filewatch
+
+import (
+	"context"
+	"os"
+	"slices"
+	"time"
+)
+
+// Watch calls onChange every time the modification time or the size
+// of any of the files changes, until ctx is done. Files are polled
+// every interval, so changes made by atomic renames are noticed as well.
+func Watch(ctx context.Context, interval time.Duration, onChange func(), filenames ...string) {
+	last := stats(filenames)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if cur := stats(filenames); !slices.Equal(cur, last) {
+				last = cur
+				onChange()
+			}
+		}
+	}
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func stats(filenames []string) []fileStat {
+	res := make([]fileStat, len(filenames))
+	for i, name := range filenames {
+		if fi, err := os.Stat(name); err == nil {
+			res[i] = fileStat{modTime: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return res
+}
@@ -0,0 +1,160 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"github.com/Karzoug/gocloudcamp/internal/server"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"nhooyr.io/websocket"
+)
+
+// EventsPath is the path of the WebSocket endpoint pushing player events.
+const EventsPath = "/v1/events"
+
+const (
+	defaultHeartbeatInterval = 30 * time.Second
+
+	// closeCodeBase is added to the status code of the failed
+	// WatchEvents call to get the WebSocket close code.
+	closeCodeBase = 4000
+)
+
+// eventsHandler pushes events of WatchEvents as JSON text messages over WebSocket.
+// The query selects events:
+//
+//	types=STATE_CHANGED,TRACK_CHANGED  event types, all if not set
+//	after_id=42                        id of the last seen event to resume after
+//	heartbeat=30s                      interval of HEARTBEAT events, 0 disables them
+//	access_token=...                   bearer token for browsers that cannot set headers
+//
+// If the call fails, the connection is closed with the code 4000 + the status code.
+func eventsHandler(client grpcapi.PlayerServiceClient, origins []string) runtime.HandlerFunc {
+	allowed := originFunc(origins)
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		req, err := watchEventsRequest(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(r, origin) && !allowed(origin) {
+			http.Error(w, "origin is not allowed", http.StatusForbidden)
+			return
+		}
+
+		// the origin is checked above against the same origins as CORS requests
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer conn.Close(websocket.StatusInternalError, "")
+
+		// incoming messages are not expected, reading only handles control frames
+		ctx := conn.CloseRead(r.Context())
+		ctx = metadata.NewOutgoingContext(ctx, eventsMetadata(r))
+		stream, err := client.WatchEvents(ctx, req)
+		if err != nil {
+			closeWithStatus(conn, err)
+			return
+		}
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				closeWithStatus(conn, err)
+				return
+			}
+			b, err := protojson.Marshal(e)
+			if err != nil {
+				closeWithStatus(conn, err)
+				return
+			}
+			if err := conn.Write(ctx, websocket.MessageText, b); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func watchEventsRequest(q url.Values) (*grpcapi.WatchEventsRequest, error) {
+	req := &grpcapi.WatchEventsRequest{
+		HeartbeatInterval: durationpb.New(defaultHeartbeatInterval),
+	}
+	for _, v := range q["types"] {
+		for _, name := range strings.Split(v, ",") {
+			t, ok := grpcapi.EventType_value[strings.ToUpper(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("unknown event type %q", name)
+			}
+			req.Types = append(req.Types, grpcapi.EventType(t))
+		}
+	}
+	if v := q.Get("after_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, errors.New("after_id must be an event id")
+		}
+		req.AfterId = id
+	}
+	if v := q.Get("heartbeat"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.New("heartbeat must be a duration")
+		}
+		req.HeartbeatInterval = nil
+		if d > 0 {
+			req.HeartbeatInterval = durationpb.New(d)
+		}
+	}
+	return req, nil
+}
+
+// eventsMetadata forwards credentials, the request id and the client address to the server.
+func eventsMetadata(r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	if v := r.Header.Get(auth.AuthorizationHeader); v != "" {
+		md.Set(auth.AuthorizationHeader, v)
+	} else if v := r.URL.Query().Get("access_token"); v != "" {
+		md.Set(auth.AuthorizationHeader, "Bearer "+v)
+	}
+	if v := r.Header.Get(auth.APIKeyHeader); v != "" {
+		md.Set(auth.APIKeyHeader, v)
+	}
+	if v := r.Header.Get(server.RequestIdHeader); v != "" {
+		md.Set(server.RequestIdHeader, v)
+	}
+	if ip := remoteIP(r); ip != "" {
+		md.Set(forwardedForHeader, ip)
+	}
+	return md
+}
+
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func closeWithStatus(conn *websocket.Conn, err error) {
+	st := status.Convert(err)
+	if st.Code() == codes.Canceled {
+		conn.Close(websocket.StatusNormalClosure, "")
+		return
+	}
+	reason := st.Message()
+	// a close frame reason is limited to 123 bytes
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	conn.Close(websocket.StatusCode(closeCodeBase+int(st.Code())), reason)
+}
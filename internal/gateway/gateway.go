@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"github.com/Karzoug/gocloudcamp/internal/server"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+// OpenAPIPath is the path the OpenAPI document is served at.
+const OpenAPIPath = "/openapi.json"
+
+// New returns the HTTP handler translating REST/JSON calls to PlayerService calls over conn
+// and pushing player events over WebSocket. Calls pass through the server interceptors,
+// so authentication, authorization, validation and auditing apply to them as to gRPC calls.
+// Cross-origin calls are accepted from the origins only, "*" accepts any origin.
+func New(ctx context.Context, conn *grpc.ClientConn, origins []string) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+	)
+	if err := grpcapi.RegisterPlayerServiceHandler(ctx, mux, conn); err != nil {
+		return nil, fmt.Errorf("register gateway handler error: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, OpenAPIPath, serveOpenAPI); err != nil {
+		return nil, fmt.Errorf("register OpenAPI handler error: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, EventsPath, eventsHandler(grpcapi.NewPlayerServiceClient(conn), origins)); err != nil {
+		return nil, fmt.Errorf("register events handler error: %w", err)
+	}
+	return withCORS(mux, origins), nil
+}
+
+// incomingHeader forwards API keys and request ids to the server in addition
+// to the headers forwarded by default (the Authorization header among them).
+func incomingHeader(key string) (string, bool) {
+	switch k := strings.ToLower(key); k {
+	case auth.APIKeyHeader, server.RequestIdHeader:
+		return k, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeader returns the request id as is, other response
+// metadata is prefixed with Grpc-Metadata- as by default.
+func outgoingHeader(key string) (string, bool) {
+	if key == server.RequestIdHeader {
+		return key, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+func serveOpenAPI(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(grpcapi.OpenAPI)
+}
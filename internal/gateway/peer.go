@@ -0,0 +1,77 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// forwardedForHeader is the metadata key of the HTTP client address.
+// The gateway appends the address the request came from to the value
+// of the X-Forwarded-For header, if the client has sent one.
+const forwardedForHeader = "x-forwarded-for"
+
+// clientAddr is the address of the HTTP client of the gateway.
+type clientAddr string
+
+func (a clientAddr) Network() string { return "tcp" }
+func (a clientAddr) String() string  { return string(a) }
+
+// UnaryPeerInterceptor replaces the peer of calls made by the gateway, which is
+// the in-memory connection, with the HTTP client, so that rate limits and the audit
+// log tell clients apart. It must be used only by the server the gateway calls,
+// as any gRPC client may set the forwarded address.
+func UnaryPeerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withClientPeer(ctx), req)
+	}
+}
+
+// StreamPeerInterceptor is the stream counterpart of UnaryPeerInterceptor.
+func StreamPeerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &peerStream{ServerStream: ss, ctx: withClientPeer(ss.Context())})
+	}
+}
+
+type peerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *peerStream) Context() context.Context {
+	return s.ctx
+}
+
+// withClientPeer returns ctx with the peer set to the last forwarded address,
+// the one the gateway has seen, other addresses are set by the client.
+func withClientPeer(ctx context.Context) context.Context {
+	vals := metadata.ValueFromIncomingContext(ctx, forwardedForHeader)
+	if len(vals) == 0 {
+		return ctx
+	}
+	addrs := strings.Split(vals[len(vals)-1], ",")
+	ip := net.ParseIP(strings.TrimSpace(addrs[len(addrs)-1]))
+	if ip == nil {
+		return ctx
+	}
+	p := &peer.Peer{Addr: clientAddr(ip.String())}
+	if old, ok := peer.FromContext(ctx); ok {
+		p.AuthInfo = old.AuthInfo
+	}
+	return peer.NewContext(ctx, p)
+}
+
+// remoteIP returns the IP address of the HTTP client, empty if it is unknown.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
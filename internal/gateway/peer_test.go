@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestWithClientPeer(t *testing.T) {
+	conn := &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}}
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{name: "no header", md: metadata.MD{}, want: conn.Addr.String()},
+		{name: "client address", md: metadata.Pairs(forwardedForHeader, "10.0.0.1"), want: "10.0.0.1"},
+		{name: "client header is ignored", md: metadata.Pairs(forwardedForHeader, "1.1.1.1, 10.0.0.1"), want: "10.0.0.1"},
+		{name: "last value", md: metadata.Pairs(forwardedForHeader, "1.1.1.1", forwardedForHeader, "10.0.0.2"), want: "10.0.0.2"},
+		{name: "ipv6", md: metadata.Pairs(forwardedForHeader, "::1"), want: "::1"},
+		{name: "not an address", md: metadata.Pairs(forwardedForHeader, "example.com"), want: conn.Addr.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(peer.NewContext(context.Background(), conn), tt.md)
+			p, ok := peer.FromContext(withClientPeer(ctx))
+			if !ok {
+				t.Fatal("no peer in context")
+			}
+			if got := p.Addr.String(); got != tt.want {
+				t.Errorf("peer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"github.com/Karzoug/gocloudcamp/internal/server"
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"github.com/rs/cors"
+	"google.golang.org/grpc"
+)
+
+// anyOrigin in the origins accepts cross-origin calls from any origin,
+// but without credentials of the browser, such as cookies and client certificates.
+const anyOrigin = "*"
+
+// GRPCWeb returns the HTTP handler serving gRPC-Web calls, server-streaming ones
+// included, with s. Cross-origin calls are accepted from the origins only.
+func GRPCWeb(s *grpc.Server, origins []string) http.Handler {
+	if !slices.Contains(origins, anyOrigin) {
+		return grpcweb.WrapServer(s, grpcweb.WithOriginFunc(originFunc(origins)))
+	}
+	// the wrapper always allows credentials, so it accepts no origin
+	// and cross-origin calls are allowed without credentials in front of it
+	h := grpcweb.WrapServer(s, grpcweb.WithOriginFunc(func(string) bool { return false }))
+	return cors.New(cors.Options{
+		AllowedOrigins: []string{anyOrigin},
+		AllowedMethods: []string{http.MethodPost},
+		AllowedHeaders: []string{"*"},
+	}).Handler(h)
+}
+
+// withCORS returns h accepting cross-origin calls from the origins.
+func withCORS(h http.Handler, origins []string) http.Handler {
+	opts := cors.Options{
+		AllowedMethods: []string{
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+			http.MethodOptions,
+		},
+		AllowedHeaders: []string{
+			"Content-Type",
+			auth.AuthorizationHeader,
+			auth.APIKeyHeader,
+			server.RequestIdHeader,
+		},
+		ExposedHeaders: []string{server.RequestIdHeader},
+	}
+	if slices.Contains(origins, anyOrigin) {
+		opts.AllowedOrigins = []string{anyOrigin}
+	} else {
+		opts.AllowOriginFunc = originFunc(origins)
+		opts.AllowCredentials = true
+	}
+	return cors.New(opts).Handler(h)
+}
+
+func originFunc(origins []string) func(origin string) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[o] = true
+	}
+	return func(origin string) bool {
+		return allowed[anyOrigin] || allowed[origin]
+	}
+}
@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/grpc"
+)
+
+func TestWithCORS_Preflight(t *testing.T) {
+	const origin = "https://player.example"
+	tests := []struct {
+		method string
+		origin string
+		want   bool
+	}{
+		{method: http.MethodGet, origin: origin, want: true},
+		{method: http.MethodPost, origin: origin, want: true},
+		{method: http.MethodPut, origin: origin, want: true},
+		{method: http.MethodPatch, origin: origin, want: true},
+		{method: http.MethodDelete, origin: origin, want: true},
+		{method: http.MethodPut, origin: "https://other.example"},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("preflight request is passed to the handler")
+	})
+	h := withCORS(next, []string{origin})
+	for _, tt := range tests {
+		t.Run(tt.method+" from "+tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/v1/player/sleep-timer", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code >= 300 {
+				t.Fatalf("status = %d, want success", w.Code)
+			}
+			allowed := w.Header().Get("Access-Control-Allow-Origin") == tt.origin &&
+				strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), tt.method)
+			if allowed != tt.want {
+				t.Errorf("allowed = %v, want %v (headers %v)", allowed, tt.want, w.Header())
+			}
+		})
+	}
+}
+
+func TestCORS_Credentials(t *testing.T) {
+	const origin = "https://player.example"
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	tests := []struct {
+		name string
+		// grpcWeb selects the gRPC-Web handler instead of the gateway
+		grpcWeb         bool
+		origins         []string
+		preflight       bool
+		wantOrigin      string
+		wantCredentials bool
+	}{
+		{
+			name:            "gateway listed origin",
+			origins:         []string{origin},
+			wantOrigin:      origin,
+			wantCredentials: true,
+		},
+		{
+			name:       "gateway any origin",
+			origins:    []string{"https://other.example", anyOrigin},
+			wantOrigin: anyOrigin,
+		},
+		{
+			name:       "gateway any origin preflight",
+			origins:    []string{anyOrigin},
+			preflight:  true,
+			wantOrigin: anyOrigin,
+		},
+		{
+			name:            "gRPC-Web listed origin",
+			grpcWeb:         true,
+			origins:         []string{origin},
+			preflight:       true,
+			wantOrigin:      origin,
+			wantCredentials: true,
+		},
+		{
+			name:       "gRPC-Web any origin",
+			grpcWeb:    true,
+			origins:    []string{anyOrigin},
+			preflight:  true,
+			wantOrigin: anyOrigin,
+		},
+		{
+			name:      "gRPC-Web not listed origin",
+			grpcWeb:   true,
+			origins:   []string{"https://other.example"},
+			preflight: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/audios", nil)
+			if tt.preflight {
+				r = httptest.NewRequest(http.MethodOptions, "/grpcapi.PlayerService/GetStatus", nil)
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			r.Header.Set("Origin", origin)
+			h := withCORS(next, tt.origins)
+			if tt.grpcWeb {
+				r.Header.Set("Access-Control-Request-Headers", "x-grpc-web")
+				s := grpc.NewServer()
+				grpcapi.RegisterPlayerServiceServer(s, grpcapi.UnimplementedPlayerServiceServer{})
+				h = GRPCWeb(s, tt.origins)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("credentials allowed = %v, want %v", got, tt.wantCredentials)
+			}
+		})
+	}
+}
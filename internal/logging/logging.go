@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+const redacted = "[REDACTED]"
+
+type loggingConfig interface {
+	LogLevel() string
+	LogFormat() string
+	LogUserText() bool
+}
+
+// New creates a logger that writes to w with the configured level and format
+// and adds the request id from the context to every record. User text is
+// redacted at every level, debug included, unless cfg.LogUserText() is true.
+func New(w io.Writer, cfg loggingConfig) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel())); err != nil {
+		return nil, fmt.Errorf("parse log level error: %w", err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch strings.ToLower(cfg.LogFormat()) {
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", cfg.LogFormat())
+	}
+
+	return slog.New(contextHandler{Handler: h, revealUserText: cfg.LogUserText()}), nil
+}
+
+// UserText wraps text provided by users (e.g. audio names). It is redacted
+// unless the logger is created by New with user text logging enabled.
+type UserText string
+
+func (t UserText) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+type requestIdKey struct{}
+
+// WithRequestId returns a copy of ctx with the request id.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestId returns the request id from ctx or empty string.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// contextHandler adds values stored in the context to records
+// and logs UserText as is if revealUserText is true.
+type contextHandler struct {
+	slog.Handler
+	revealUserText bool
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.revealUserText {
+		revealed := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			revealed.AddAttrs(reveal(a))
+			return true
+		})
+		r = revealed
+	}
+	if id := RequestId(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if h.revealUserText {
+		attrs = revealAll(attrs)
+	}
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs), revealUserText: h.revealUserText}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name), revealUserText: h.revealUserText}
+}
+
+// reveal replaces UserText values in a with the text.
+func reveal(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindLogValuer:
+		if t, ok := a.Value.Any().(UserText); ok {
+			a.Value = slog.StringValue(string(t))
+		}
+	case slog.KindGroup:
+		a.Value = slog.GroupValue(revealAll(a.Value.Group())...)
+	}
+	return a
+}
+
+func revealAll(attrs []slog.Attr) []slog.Attr {
+	res := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		res[i] = reveal(a)
+	}
+	return res
+}
@@ -6,4 +6,25 @@ type Audio struct {
 	Id       string        `json:"id"`
 	Name     string        `json:"name"`
 	Duration time.Duration `json:"duration"`
+	// Version is increased on every change of the audio.
+	Version uint64 `json:"version"`
+}
+
+// DeletedAudio is an audio kept in the trash after deletion.
+type DeletedAudio struct {
+	Audio
+	// Index is the position the audio had in the playlist.
+	Index     int
+	DeletedAt time.Time
+	// ExpiresAt is the time the audio is removed from the trash permanently.
+	ExpiresAt time.Time
+}
+
+// SleepTimer pauses playback at the time or after the number of tracks.
+type SleepTimer struct {
+	// At, if not zero, is the time playback is paused at.
+	At time.Time `json:"at"`
+	// Tracks, if not zero, is the number of tracks left to be played to the end
+	// before playback is paused, 1 means at the end of the current track.
+	Tracks int `json:"tracks"`
 }
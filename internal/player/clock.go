@@ -0,0 +1,9 @@
+package player
+
+import "github.com/Karzoug/gocloudcamp/pkg/clock"
+
+// Clock is the source of time of the playback, it is replaced in tests.
+type Clock = clock.Clock
+
+// Timer sends the time on its channel once it fires.
+type Timer = clock.Timer
@@ -5,4 +5,10 @@ import "errors"
 var (
 	ErrNoAudio      = errors.New("no audio to play")
 	ErrPlayerClosed = errors.New("player closed")
+	// ErrPositionOutOfRange is returned by Seek if the position is not within the current audio.
+	ErrPositionOutOfRange = errors.New("position is out of the current audio")
+	// ErrSleepTimerInPast is returned by SetSleepTimer if the time of the timer has passed.
+	ErrSleepTimerInPast = errors.New("sleep timer time is in the past")
+	// ErrQueueFull is returned if the queue of commands waiting to be handled is full.
+	ErrQueueFull = errors.New("player command queue is full")
 )
@@ -0,0 +1,76 @@
+package player
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	tracksStartedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "player_tracks_started_total",
+		Help: "Total number of tracks started.",
+	})
+	tracksCompletedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "player_tracks_completed_total",
+		Help: "Total number of tracks played to the end.",
+	})
+	tracksSkippedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "player_tracks_skipped_total",
+		Help: "Total number of tracks skipped by Next or Prev commands.",
+	})
+)
+
+var (
+	stateDesc = prometheus.NewDesc(
+		"player_state",
+		"Current state of the player: 0 - no active audio, 1 - playing, 2 - paused, 3 - closed.",
+		nil, nil)
+	trackDurationDesc = prometheus.NewDesc(
+		"player_current_track_duration_seconds",
+		"Duration of the current track.",
+		nil, nil)
+	trackPositionDesc = prometheus.NewDesc(
+		"player_current_track_position_seconds",
+		"Playback position in the current track.",
+		nil, nil)
+	queueDepthDesc = prometheus.NewDesc(
+		"player_command_queue_depth",
+		"Number of commands waiting to be handled by the player.",
+		nil, nil)
+	playlistSizeDesc = prometheus.NewDesc(
+		"playlist_size",
+		"Number of audios in the playlist.",
+		nil, nil)
+)
+
+type collector struct {
+	p *Player
+}
+
+// NewCollector returns a collector of the player gauges.
+func NewCollector(p *Player) prometheus.Collector {
+	return collector{p: p}
+}
+
+func (c collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stateDesc
+	ch <- trackDurationDesc
+	ch <- trackPositionDesc
+	ch <- queueDepthDesc
+	ch <- playlistSizeDesc
+}
+
+func (c collector) Collect(ch chan<- prometheus.Metric) {
+	st := c.p.Status()
+	ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, float64(st.State))
+	var duration float64
+	if st.Audio != nil {
+		duration = st.Audio.Duration.Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(trackDurationDesc, prometheus.GaugeValue, duration)
+	ch <- prometheus.MustNewConstMetric(trackPositionDesc, prometheus.GaugeValue, st.Position.Seconds())
+	ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(c.p.QueueLen()))
+	ch <- prometheus.MustNewConstMetric(playlistSizeDesc, prometheus.GaugeValue, float64(c.p.Playlist.Len(context.Background())))
+}
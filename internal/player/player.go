@@ -3,48 +3,102 @@ package player
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
 
+	"github.com/Karzoug/gocloudcamp/internal/events"
+	"github.com/Karzoug/gocloudcamp/internal/logging"
 	"github.com/Karzoug/gocloudcamp/internal/models"
 	"github.com/Karzoug/gocloudcamp/internal/playlist"
-	"github.com/ivahaev/timer"
+	"github.com/Karzoug/gocloudcamp/pkg/clock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var mockPlayFnc = func(a models.Audio, signals playerSignals) error {
-	log.Printf("New audio loaded: name '%s', duration '%s'", a.Name, a.Duration)
-	var (
-		err error
-		t   *timer.Timer
-	)
+var tracer = otel.Tracer("github.com/Karzoug/gocloudcamp/internal/player")
 
-	go func() {
+var mockPlayFnc = func(a models.Audio, signals playerSignals, clock Clock, logger *slog.Logger) error {
+	logger = logger.With(slog.String("audio_id", a.Id))
+	logger.Info("audio loaded", slog.Any("name", logging.UserText(a.Name)), slog.Duration("duration", a.Duration))
+	var err error
 
-		select {
-		case <-signals.closeCh:
-			log.Print("Audio closed")
-			return
-		case <-signals.playCh:
-			log.Print("Audio started")
-			t = timer.NewTimer(a.Duration)
-			t.Start()
-			defer t.Stop()
+	go func() {
+		// remaining is the playback time left since startedAt,
+		// t fires at the end of the audio while it is playing
+		var (
+			remaining = a.Duration
+			startedAt time.Time
+			t         Timer
+		)
+		start := func(at time.Time) {
+			if t != nil {
+				return
+			}
+			startedAt = at
+			t = clock.NewTimer(remaining - clock.Now().Sub(at))
+		}
+		stop := func(at time.Time) {
+			if t == nil {
+				return
+			}
+			t.Stop()
+			t = nil
+			remaining -= at.Sub(startedAt)
 		}
 
 		for {
+			var ended <-chan time.Time
+			if t != nil {
+				ended = t.C()
+			}
 			select {
 			case <-signals.closeCh:
-				log.Print("Audio closed")
-				return
-			case <-t.C:
-				log.Print("Audio ended")
-				signals.endCh <- struct{}{}
+				logger.Info("audio closed")
+				stop(clock.Now())
 				return
-			case <-signals.pauseCh:
-				log.Print("Audio paused")
-				t.Pause()
-			case <-signals.playCh:
-				log.Print("Audio started")
-				t.Start()
+			case <-ended:
+				logger.Info("audio ended")
+				// the loop may be sending a signal at the same time,
+				// signals other than close are too late for the ended audio
+				for {
+					select {
+					case signals.endCh <- struct{}{}:
+						return
+					case <-signals.closeCh:
+						return
+					case <-signals.playCh:
+					case <-signals.pauseCh:
+					case <-signals.seekCh:
+					case <-signals.durationCh:
+					}
+				}
+			case at := <-signals.pauseCh:
+				logger.Info("audio paused")
+				stop(at)
+			case at := <-signals.playCh:
+				logger.Info("audio started")
+				start(at)
+			case s := <-signals.seekCh:
+				logger.Info("audio seeked", slog.Duration("position", s.position))
+				playing := t != nil
+				stop(s.at)
+				remaining = a.Duration - s.position
+				if playing {
+					start(s.at)
+				}
+			case d := <-signals.durationCh:
+				logger.Info("audio duration changed", slog.Duration("duration", d.duration))
+				playing := t != nil
+				stop(d.at)
+				// the audio ends at once if it has been played longer than the new duration
+				remaining += d.duration - a.Duration
+				a.Duration = d.duration
+				if playing {
+					start(d.at)
+				}
 			}
 		}
 	}()
@@ -54,33 +108,95 @@ var mockPlayFnc = func(a models.Audio, signals playerSignals) error {
 
 type Player struct {
 	Playlist playlist.Playlist
-	playFnc  func(models.Audio, playerSignals) error
+	playFnc  func(models.Audio, playerSignals, Clock, *slog.Logger) error
+	clock    Clock
+	logger   *slog.Logger
 
 	commandsCh chan commandMsg
-	state      state
-	signals    playerSignals
+	// editsCh receives Update and Delete, which are neither limited
+	// by the queue of commands nor collected with Next and Prev.
+	editsCh chan commandMsg
+	signals playerSignals
+
+	// state is changed only by the loop goroutine,
+	// mtx guards it for readers from other goroutines.
+	mtx       sync.RWMutex
+	state     State
+	elapsed   time.Duration
+	startedAt time.Time
+
+	// coalesceWindow is the time Next and Prev commands are collected
+	// to be handled at once, zero disables coalescing.
+	coalesceWindow time.Duration
+
+	events *events.Bus
+
+	// sleep is the sleep timer, it is changed only by the loop goroutine
+	// and guarded by mtx; sleepTimer fires when a time sleep timer is due.
+	sleep      *models.SleepTimer
+	sleepTimer Timer
+	sleepStore SleepTimerStore
 
 	closePlayerCh chan struct{}
 }
 
-func New(pl playlist.Playlist) *Player {
+// Option configures the player.
+type Option func(*Player)
+
+// WithCoalesceWindow makes the player collect Next and Prev commands sent within
+// the window after the first one and skip by their total at once,
+// e.g. five Next commands become one skip by five tracks.
+func WithCoalesceWindow(d time.Duration) Option {
+	return func(p *Player) {
+		p.coalesceWindow = d
+	}
+}
+
+// WithClock replaces the system clock timing the playback.
+func WithClock(c Clock) Option {
+	return func(p *Player) {
+		p.clock = c
+	}
+}
+
+// WithEvents makes the player publish state and track changes to the bus.
+func WithEvents(b *events.Bus) Option {
+	return func(p *Player) {
+		p.events = b
+	}
+}
+
+func New(pl playlist.Playlist, logger *slog.Logger, opts ...Option) *Player {
 	p := Player{
 		Playlist:   pl,
+		logger:     logger,
 		commandsCh: make(chan commandMsg, 10),
+		editsCh:    make(chan commandMsg),
 		signals: playerSignals{
-			playCh:  make(chan struct{}),
-			pauseCh: make(chan struct{}),
+			playCh:  make(chan time.Time),
+			pauseCh: make(chan time.Time),
 			closeCh: make(chan struct{}),
 			endCh:   make(chan struct{}),
+			seekCh:  make(chan seekSignal),
+
+			durationCh: make(chan durationSignal),
 		},
 		closePlayerCh: make(chan struct{}),
 		playFnc:       mockPlayFnc,
+		clock:         clock.New(),
 	}
-	if a := p.Playlist.Current(); a != nil {
-		if err := p.handleCurrentElement(); err != nil {
-			p.state = noActiveAudio
+	for _, opt := range opts {
+		opt(&p)
+	}
+	if p.sleepStore != nil {
+		// a time timer that has passed while the player was stopped fires at once
+		p.setSleep(context.Background(), p.sleepStore.SleepTimer())
+	}
+	if a := p.Playlist.Current(context.Background()); a != nil {
+		if err := p.handleCurrentElement(context.Background()); err != nil {
+			p.setState(NoActiveAudio)
 		} else {
-			p.state = paused
+			p.setState(Paused)
 		}
 
 	}
@@ -95,41 +211,104 @@ func (p *Player) Close() error {
 	return p.Playlist.Close()
 }
 
+// IsClosed reports whether the player has been closed.
+func (p *Player) IsClosed() bool {
+	select {
+	case <-p.closePlayerCh:
+		return true
+	default:
+		return false
+	}
+}
+
 // Play начинает воспроизведение
 func (p *Player) Play(ctx context.Context) error {
-	return p.addCommand(ctx, Play)
+	return p.addCommand(ctx, commandMsg{command: Play})
 }
 
 // Pause приостанавливает воспроизведение
-func (p Player) Pause(ctx context.Context) error {
-	return p.addCommand(ctx, Pause)
+func (p *Player) Pause(ctx context.Context) error {
+	return p.addCommand(ctx, commandMsg{command: Pause})
 }
 
 // Next позволяет воспроизвести след песню
 func (p *Player) Next(ctx context.Context) error {
-	return p.addCommand(ctx, Next)
+	return p.addCommand(ctx, commandMsg{command: Next})
 }
 
 // Prev позволяет воспроизвести предыдущую песню
 func (p *Player) Prev(ctx context.Context) error {
-	return p.addCommand(ctx, Prev)
+	return p.addCommand(ctx, commandMsg{command: Prev})
 }
 
-func (p Player) addCommand(ctx context.Context, c command) error {
-	errCh := make(chan error)
-	msg := commandMsg{
-		command: c,
-		err:     errCh,
+// Seek continues playback of the current audio from the position,
+// the state of the player is not changed.
+func (p *Player) Seek(ctx context.Context, position time.Duration) error {
+	return p.addCommand(ctx, commandMsg{command: Seek, position: position})
+}
+
+// UpdateAudio replaces the audio in the playlist. A change of the current audio
+// applies immediately: the playback continues from the same position with
+// the new duration, the audio ends at once if the position is past it.
+// Unlike commands, edits wait for the player rather than fail on a full queue.
+func (p *Player) UpdateAudio(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	if err := p.addEdit(ctx, commandMsg{command: Update, audio: &a}); err != nil {
+		return nil, err
 	}
+	return &a, nil
+}
 
+// DeleteAudio removes the audio from the playlist. Deleting the current audio
+// makes the player load the following audio and play it if it was playing,
+// the player stops if there is no following audio.
+func (p *Player) DeleteAudio(ctx context.Context, id string, version uint64) error {
+	return p.addEdit(ctx, commandMsg{command: Delete, id: id, version: version})
+}
+
+func (p *Player) addCommand(ctx context.Context, msg commandMsg) error {
+	msg, errCh := p.newMsg(ctx, msg)
 	select {
 	case <-ctx.Done():
+		msg.queueSpan.End()
 		return ctx.Err()
 	case <-p.closePlayerCh:
+		msg.queueSpan.End()
 		return ErrPlayerClosed
 	case p.commandsCh <- msg:
+	default:
+		// callers are not kept waiting behind a flood of commands
+		msg.queueSpan.End()
+		return ErrQueueFull
 	}
+	return p.wait(ctx, errCh)
+}
 
+// addEdit passes the edit of the playlist to the loop as soon as it is free.
+func (p *Player) addEdit(ctx context.Context, msg commandMsg) error {
+	msg, errCh := p.newMsg(ctx, msg)
+	select {
+	case <-ctx.Done():
+		msg.queueSpan.End()
+		return ctx.Err()
+	case <-p.closePlayerCh:
+		msg.queueSpan.End()
+		return ErrPlayerClosed
+	case p.editsCh <- msg:
+	}
+	return p.wait(ctx, errCh)
+}
+
+func (p *Player) newMsg(ctx context.Context, msg commandMsg) (commandMsg, chan error) {
+	queueCtx, queueSpan := tracer.Start(ctx, "player.queue "+msg.command.String())
+	errCh := make(chan error)
+	msg.ctx = queueCtx
+	msg.queueSpan = queueSpan
+	msg.err = errCh
+	return msg, errCh
+}
+
+// wait waits for the result of the queued message.
+func (p *Player) wait(ctx context.Context, errCh chan error) error {
 	select {
 	case <-ctx.Done():
 		go func() {
@@ -147,112 +326,322 @@ func (p *Player) loop() {
 	for {
 		select {
 		case <-p.closePlayerCh:
-			p.state = closed
+			p.setState(Closed)
 			close(p.signals.closeCh)
 			return
 		case c := <-p.commandsCh:
-			switch c.command {
-			case Play:
-				p.play(c.err)
-			case Pause:
-				p.pause(c.err)
-			case Next:
-				p.next(c.err)
-			case Prev:
-				p.prev(c.err)
-			}
+			p.handle(c)
+		case c := <-p.editsCh:
+			p.handle(c)
 		case <-p.signals.endCh:
-			p.state = noActiveAudio
-			errCh := make(chan error)
-			go func() {
-				<-errCh
-			}()
-			p.next(errCh)
+			ctx, span := tracer.Start(context.Background(), "player.audio_ended")
+			tracksCompletedTotal.Inc()
+			p.setState(NoActiveAudio)
+			play := !p.trackEnded(ctx)
+			p.skip(ctx, 1, play, make(chan error, 1))
+			span.End()
+		case <-p.sleepTimerC():
+			ctx, span := tracer.Start(context.Background(), "player.sleep_timer")
+			p.sleepTimerFired(ctx)
+			span.End()
 		}
 	}
 }
 
-func (p *Player) play(errCh chan error) {
+func (p *Player) handle(c commandMsg) {
+	c.queueSpan.End()
+	if (c.command == Next || c.command == Prev) && p.coalesceWindow > 0 {
+		p.handleSkips(c)
+		return
+	}
+
+	ctx, span := tracer.Start(c.ctx, "player.handle "+c.command.String())
+	defer span.End()
+	p.logger.DebugContext(ctx, "handle command", slog.String("command", c.command.String()))
+
+	switch c.command {
+	case Play:
+		p.play(ctx, c.err)
+	case Pause:
+		p.pause(ctx, c.err)
+	case Next:
+		p.skip(ctx, 1, true, c.err)
+	case Prev:
+		p.skip(ctx, -1, true, c.err)
+	case Seek:
+		p.seek(ctx, c.position, c.err)
+	case Update:
+		p.update(ctx, c.audio, c.err)
+	case Delete:
+		p.delete(ctx, c.id, c.version, c.err)
+	case SetSleepTimer:
+		p.setSleepTimer(ctx, c.sleep, c.position, c.err)
+	case CancelSleepTimer:
+		p.cancelSleepTimer(ctx, c.err)
+	}
+}
+
+// handleSkips collects Next and Prev commands received within the coalesce
+// window after the first one and skips by their total at once.
+// All collected commands get the same result. Edits received meanwhile
+// are handled at once, the skip starts from the current audio after them.
+func (p *Player) handleSkips(first commandMsg) {
+	msgs := []commandMsg{first}
+	var pending *commandMsg
+
+	timer := p.clock.NewTimer(p.coalesceWindow)
+	defer timer.Stop()
+collect:
+	for {
+		select {
+		case c := <-p.commandsCh:
+			c.queueSpan.End()
+			if c.command != Next && c.command != Prev {
+				pending = &c
+				break collect
+			}
+			msgs = append(msgs, c)
+		case c := <-p.editsCh:
+			p.handle(c)
+		case <-timer.C():
+			break collect
+		case <-p.closePlayerCh:
+			break collect
+		}
+	}
+
+	var steps int
+	for _, m := range msgs {
+		if m.command == Next {
+			steps++
+		} else {
+			steps--
+		}
+	}
+
+	ctx, span := tracer.Start(first.ctx, "player.handle skip",
+		trace.WithAttributes(attribute.Int("steps", steps), attribute.Int("commands", len(msgs))))
+	p.logger.DebugContext(ctx, "handle coalesced commands", slog.Int("steps", steps), slog.Int("commands", len(msgs)))
+	errCh := make(chan error, 1)
+	p.skip(ctx, steps, true, errCh)
+	err := <-errCh
+	span.End()
+	for _, m := range msgs {
+		m.err <- err
+	}
+
+	if pending != nil {
+		p.handle(*pending)
+	}
+}
+
+func (p *Player) play(ctx context.Context, errCh chan error) {
 	switch p.state {
-	case paused:
-	case noActiveAudio:
-		if p.Playlist.Current() == nil {
-			p.Playlist.CurrentToFront()
+	case Paused:
+	case NoActiveAudio:
+		if p.Playlist.Current(ctx) == nil {
+			p.Playlist.CurrentToFront(ctx)
 		}
-		if err := p.handleCurrentElement(); err != nil {
+		if err := p.handleCurrentElement(ctx); err != nil {
 			errCh <- err
 			return
 		}
+		tracksStartedTotal.Inc()
 	default:
 		errCh <- nil
 		return
 	}
 
-	p.signals.playCh <- struct{}{}
-	p.state = playing
+	signal(ctx, "play", p.signals.playCh, p.clock.Now())
+	p.setState(Playing)
 	errCh <- nil
 }
 
-func (p *Player) pause(errCh chan error) {
-	if p.state != playing {
+func (p *Player) pause(ctx context.Context, errCh chan error) {
+	if p.state != Playing {
 		errCh <- nil
 		return
 	}
-	p.signals.pauseCh <- struct{}{}
-	p.state = paused
+	signal(ctx, "pause", p.signals.pauseCh, p.clock.Now())
+	p.setState(Paused)
 	errCh <- nil
 }
 
-func (p *Player) next(errCh chan error) {
+// skip moves the current audio by steps forward (or backward if steps
+// is negative) and starts playing it if play is true.
+func (p *Player) skip(ctx context.Context, steps int, play bool, errCh chan error) {
+	if steps == 0 {
+		errCh <- nil
+		return
+	}
+
 	switch p.state {
-	case playing, paused:
-		p.signals.closeCh <- struct{}{}
-		p.state = noActiveAudio
-	case noActiveAudio:
+	case Playing, Paused:
+		signal(ctx, "close", p.signals.closeCh, struct{}{})
+		p.setState(NoActiveAudio)
+		// a coalesced skip passes over as many tracks as its steps
+		tracksSkippedTotal.Add(math.Abs(float64(steps)))
+	case NoActiveAudio:
 	default:
 		errCh <- nil
 		return
 	}
 
-	p.Playlist.CurrentToNext()
-	if err := p.handleCurrentElement(); err != nil {
+	for ; steps > 0; steps-- {
+		p.Playlist.CurrentToNext(ctx)
+	}
+	for ; steps < 0; steps++ {
+		p.Playlist.CurrentToPrev(ctx)
+	}
+	if err := p.handleCurrentElement(ctx); err != nil {
 		errCh <- err
 		return
 	}
-	p.state = paused
-	p.signals.playCh <- struct{}{}
-	p.state = playing
+	p.setState(Paused)
+	if play {
+		signal(ctx, "play", p.signals.playCh, p.clock.Now())
+		p.setState(Playing)
+		tracksStartedTotal.Inc()
+	}
 	errCh <- nil
 }
 
-func (p *Player) prev(errCh chan error) {
-	switch p.state {
-	case playing, paused:
-		p.signals.closeCh <- struct{}{}
-		p.state = noActiveAudio
-	case noActiveAudio:
-	default:
+func (p *Player) seek(ctx context.Context, position time.Duration, errCh chan error) {
+	if p.state != Playing && p.state != Paused {
+		errCh <- ErrNoAudio
+		return
+	}
+	if a := p.Playlist.Current(ctx); a == nil || position < 0 || position >= a.Duration {
+		errCh <- ErrPositionOutOfRange
+		return
+	}
+
+	now := p.clock.Now()
+	signal(ctx, "seek", p.signals.seekCh, seekSignal{position: position, at: now})
+
+	p.mtx.Lock()
+	p.elapsed = position
+	p.startedAt = now
+	p.mtx.Unlock()
+	errCh <- nil
+}
+
+// update replaces the audio, a new duration of the current audio
+// is signalled to its goroutine.
+func (p *Player) update(ctx context.Context, a *models.Audio, errCh chan error) {
+	cur := p.Playlist.Current(ctx)
+	res, err := p.Playlist.Update(ctx, *a)
+	if err != nil {
+		errCh <- err
+		return
+	}
+	*a = *res
+
+	if cur != nil && cur.Id == res.Id && cur.Duration != res.Duration && (p.state == Playing || p.state == Paused) {
+		signal(ctx, "duration", p.signals.durationCh, durationSignal{duration: res.Duration, at: p.clock.Now()})
+	}
+	errCh <- nil
+}
+
+// delete removes the audio, the deleted current audio is closed
+// and the following one, which the playlist makes current, is loaded.
+func (p *Player) delete(ctx context.Context, id string, version uint64, errCh chan error) {
+	cur := p.Playlist.Current(ctx)
+	if err := p.Playlist.Delete(ctx, id, version); err != nil {
+		errCh <- err
+		return
+	}
+	if cur == nil || cur.Id != id {
 		errCh <- nil
 		return
 	}
 
-	p.Playlist.CurrentToPrev()
-	if err := p.handleCurrentElement(); err != nil {
+	prevState := p.state
+	switch prevState {
+	case Playing, Paused:
+		signal(ctx, "close", p.signals.closeCh, struct{}{})
+		p.setState(NoActiveAudio)
+	default:
+		errCh <- nil
+		return
+	}
+	if p.Playlist.Current(ctx) == nil {
+		// the last audio is deleted, the player stops
+		errCh <- nil
+		return
+	}
+	if err := p.handleCurrentElement(ctx); err != nil {
 		errCh <- err
 		return
 	}
-	p.state = paused
-	p.signals.playCh <- struct{}{}
-	p.state = playing
+	p.setState(Paused)
+	if prevState == Playing {
+		signal(ctx, "play", p.signals.playCh, p.clock.Now())
+		p.setState(Playing)
+		tracksStartedTotal.Inc()
+	}
 	errCh <- nil
 }
 
-func (p *Player) handleCurrentElement() error {
-	if p.Playlist.Current() == nil {
+// Status returns a snapshot of the player state.
+func (p *Player) Status() Status {
+	p.mtx.RLock()
+	st := Status{State: p.state, Position: p.elapsed}
+	if p.state == Playing {
+		st.Position += p.clock.Now().Sub(p.startedAt)
+	}
+	p.mtx.RUnlock()
+
+	if st.State == Playing || st.State == Paused {
+		st.Audio = p.Playlist.Current(context.Background())
+	}
+	return st
+}
+
+// QueueLen returns the number of commands waiting to be handled.
+func (p *Player) QueueLen() int {
+	return len(p.commandsCh)
+}
+
+func (p *Player) setState(s State) {
+	p.mtx.Lock()
+	changed := p.state != s
+	switch s {
+	case Playing:
+		if p.state != Playing {
+			p.startedAt = p.clock.Now()
+		}
+	case Paused:
+		if p.state == Playing {
+			p.elapsed += p.clock.Now().Sub(p.startedAt)
+		}
+	default:
+		p.elapsed = 0
+	}
+	p.state = s
+	p.mtx.Unlock()
+
+	if changed {
+		p.events.Publish(events.Event{Type: events.StateChanged, State: s.String()})
+	}
+}
+
+// signal sends a signal to the audio goroutine, the span shows
+// how long the loop waits for the goroutine to receive it.
+func signal[T any](ctx context.Context, name string, ch chan T, v T) {
+	_, span := tracer.Start(ctx, "player.signal "+name)
+	defer span.End()
+	ch <- v
+}
+
+func (p *Player) handleCurrentElement(ctx context.Context) error {
+	a := p.Playlist.Current(ctx)
+	if a == nil {
 		return ErrNoAudio
 	}
-	if err := p.playFnc(*p.Playlist.Current(), p.signals); err != nil {
+	if err := p.playFnc(*a, p.signals, p.clock, p.logger); err != nil {
 		return fmt.Errorf("handle audio problem: %w", err)
 	}
+	p.events.Publish(events.Event{Type: events.TrackChanged, Audio: a})
 	return nil
 }
@@ -0,0 +1,418 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"github.com/Karzoug/gocloudcamp/internal/playlist/memory"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeClock is a clock that moves only by advance.
+type fakeClock struct {
+	mtx    sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// created receives the duration of every timer created.
+	created chan time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		created: make(chan time.Duration, 100),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	c.created <- d
+	return t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mtx.Lock()
+	c.now = c.now.Add(d)
+	var due []*fakeTimer
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.mtx.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].deadline.Before(due[j].deadline) })
+	for _, t := range due {
+		t.ch <- t.deadline
+	}
+}
+
+// waitTimer waits until a timer of d is created.
+func (c *fakeClock) waitTimer(t *testing.T, d time.Duration) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case got := <-c.created:
+			if got == d {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no timer of %s is created", d)
+		}
+	}
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mtx.Lock()
+	defer t.clock.mtx.Unlock()
+	for i, ct := range t.clock.timers {
+		if ct == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func withPlayFnc(f func(models.Audio, playerSignals, Clock, *slog.Logger) error) Option {
+	return func(p *Player) {
+		p.playFnc = f
+	}
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTestPlayer returns a player of n audios of a minute named "0", "1" and so on.
+func newTestPlayer(t *testing.T, n int, opts ...Option) *Player {
+	t.Helper()
+	pl := memory.New(discardLogger)
+	for i := 0; i < n; i++ {
+		if _, err := pl.Add(context.Background(), models.Audio{Name: fmt.Sprint(i), Duration: time.Minute}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := New(pl, discardLogger, opts...)
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// send puts the command to the queue without waiting for its result.
+func send(t *testing.T, p *Player, c command) chan error {
+	t.Helper()
+	errCh := make(chan error, 1)
+	select {
+	case p.commandsCh <- commandMsg{
+		ctx:       context.Background(),
+		queueSpan: trace.SpanFromContext(context.Background()),
+		command:   c,
+		err:       errCh,
+	}:
+	default:
+		t.Fatal("command queue is full")
+	}
+	return errCh
+}
+
+func receive(t *testing.T, errCh chan error) error {
+	t.Helper()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("command is not handled")
+		return nil
+	}
+}
+
+func checkStatus(t *testing.T, p *Player, state State, name string) {
+	t.Helper()
+	st := p.Status()
+	if st.State != state || st.Audio == nil || st.Audio.Name != name {
+		t.Errorf("got status %s with audio %v, want %s with audio %s", st.State, st.Audio, state, name)
+	}
+}
+
+func TestPlayer_CoalesceSkips(t *testing.T) {
+	const window = 200 * time.Millisecond
+	tests := []struct {
+		name     string
+		commands []command
+		// advance is true if the window must pass to end the collection
+		advance bool
+		state   State
+		audio   string
+		// skipped is the increase of the skipped tracks counter
+		skipped float64
+	}{
+		{name: "single next", commands: []command{Next}, advance: true, state: Playing, audio: "1", skipped: 1},
+		{name: "five next", commands: []command{Next, Next, Next, Next, Next}, advance: true, state: Playing, audio: "5", skipped: 5},
+		{name: "next and prev", commands: []command{Next, Next, Next, Prev}, advance: true, state: Playing, audio: "2", skipped: 2},
+		{name: "cancelling out", commands: []command{Next, Prev}, advance: true, state: Playing, audio: "0"},
+		{name: "ended by other command", commands: []command{Next, Next, Pause}, state: Paused, audio: "2", skipped: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			p := newTestPlayer(t, 10, WithClock(clock), WithCoalesceWindow(window))
+			if err := p.Play(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			skipped := testutil.ToFloat64(tracksSkippedTotal)
+			errChs := make([]chan error, len(tt.commands))
+			for i, c := range tt.commands {
+				errChs[i] = send(t, p, c)
+			}
+			if tt.advance {
+				clock.waitTimer(t, window)
+				// all commands are collected before the window passes
+				for p.QueueLen() > 0 {
+					time.Sleep(time.Millisecond)
+				}
+				clock.advance(window)
+			}
+			for i, errCh := range errChs {
+				if err := receive(t, errCh); err != nil {
+					t.Fatalf("command %d: %v", i, err)
+				}
+			}
+			checkStatus(t, p, tt.state, tt.audio)
+			if got := testutil.ToFloat64(tracksSkippedTotal) - skipped; got != tt.skipped {
+				t.Errorf("skipped tracks = %v, want %v", got, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestPlayer_SkipsWithoutCoalescing(t *testing.T) {
+	p := newTestPlayer(t, 10, WithClock(newFakeClock()))
+	if err := p.Play(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Prev(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(t, p, Playing, "2")
+}
+
+func TestPlayer_QueueFull(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	// the first audio blocks the loop until released
+	play := func(a models.Audio, signals playerSignals, clock Clock, logger *slog.Logger) error {
+		once.Do(func() {
+			close(entered)
+			<-release
+		})
+		return mockPlayFnc(a, signals, clock, logger)
+	}
+	p := newTestPlayer(t, 3, WithClock(newFakeClock()), withPlayFnc(play))
+
+	playErr := make(chan error, 1)
+	go func() { playErr <- p.Play(context.Background()) }()
+	<-entered
+
+	errChs := make([]chan error, cap(p.commandsCh))
+	for i := range errChs {
+		errChs[i] = send(t, p, Pause)
+	}
+	if err := p.Next(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("got error %v, want %v", err, ErrQueueFull)
+	}
+
+	close(release)
+	if err := receive(t, playErr); err != nil {
+		t.Fatal(err)
+	}
+	for _, errCh := range errChs {
+		if err := receive(t, errCh); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the queue accepts commands again
+	if err := p.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(t, p, Playing, "1")
+}
+
+// idOf returns the id of the audio with the name.
+func idOf(t *testing.T, p *Player, name string) string {
+	t.Helper()
+	audios, _, err := p.Playlist.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range audios {
+		if a.Name == name {
+			return a.Id
+		}
+	}
+	t.Fatalf("no audio %s", name)
+	return ""
+}
+
+// currentName returns the name of the current audio, empty if there is none.
+func currentName(p *Player) string {
+	if a := p.Status().Audio; a != nil {
+		return a.Name
+	}
+	return ""
+}
+
+func TestPlayer_DeleteAudio(t *testing.T) {
+	tests := []struct {
+		name   string
+		pause  bool
+		delete string
+		state  State
+		// audio is the current audio after the deletion, empty if there is none
+		audio string
+	}{
+		{name: "current playing", delete: "1", state: Playing, audio: "2"},
+		{name: "current paused", pause: true, delete: "1", state: Paused, audio: "2"},
+		{name: "other", delete: "0", state: Playing, audio: "1"},
+		{name: "current last", delete: "2", state: NoActiveAudio},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p := newTestPlayer(t, 3, WithClock(newFakeClock()))
+			if err := p.Play(ctx); err != nil {
+				t.Fatal(err)
+			}
+			// the audio before the deleted one is current in the last case
+			current := "1"
+			if tt.audio == "" {
+				current = tt.delete
+			}
+			for currentName(p) != current {
+				if err := p.Next(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.pause {
+				if err := p.Pause(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := p.DeleteAudio(ctx, idOf(t, p, tt.delete), 0); err != nil {
+				t.Fatal(err)
+			}
+			if tt.audio == "" {
+				if st := p.Status(); st.State != tt.state || st.Audio != nil {
+					t.Errorf("got status %s with audio %v, want %s without audio", st.State, st.Audio, tt.state)
+				}
+				return
+			}
+			checkStatus(t, p, tt.state, tt.audio)
+		})
+	}
+}
+
+func TestPlayer_EditsWithFullQueue(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	play := func(a models.Audio, signals playerSignals, clock Clock, logger *slog.Logger) error {
+		once.Do(func() {
+			close(entered)
+			<-release
+		})
+		return mockPlayFnc(a, signals, clock, logger)
+	}
+	p := newTestPlayer(t, 3, WithClock(newFakeClock()), withPlayFnc(play))
+
+	playErr := make(chan error, 1)
+	go func() { playErr <- p.Play(context.Background()) }()
+	<-entered
+	for i := 0; i < cap(p.commandsCh); i++ {
+		send(t, p, Pause)
+	}
+
+	id := idOf(t, p, "2")
+	editErr := make(chan error, 1)
+	go func() {
+		_, err := p.UpdateAudio(context.Background(), models.Audio{Id: id, Name: "2*", Duration: time.Minute})
+		editErr <- err
+	}()
+	close(release)
+	if err := receive(t, editErr); err != nil {
+		t.Fatalf("got error %v, want the edit to wait for the player", err)
+	}
+	if err := receive(t, playErr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayer_EditsDuringCoalescing(t *testing.T) {
+	const window = 200 * time.Millisecond
+	clock := newFakeClock()
+	p := newTestPlayer(t, 4, WithClock(clock), WithCoalesceWindow(window))
+	ctx := context.Background()
+	if err := p.Play(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	nextErr := send(t, p, Next)
+	clock.waitTimer(t, window)
+	// the edit is handled while Next commands are collected
+	// rather than after the window passes
+	deleteErr := make(chan error, 1)
+	go func() { deleteErr <- p.DeleteAudio(ctx, idOf(t, p, "0"), 0) }()
+	if err := receive(t, deleteErr); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(t, p, Playing, "1")
+
+	clock.advance(window)
+	if err := receive(t, nextErr); err != nil {
+		t.Fatal(err)
+	}
+	checkStatus(t, p, Playing, "2")
+}
@@ -0,0 +1,143 @@
+package player
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+)
+
+// SleepTimerStore keeps the sleep timer across restarts of the player.
+type SleepTimerStore interface {
+	// SleepTimer returns the kept timer, nil if there is none.
+	SleepTimer() *models.SleepTimer
+	// SetSleepTimer keeps the timer, nil removes the kept one.
+	SetSleepTimer(t *models.SleepTimer) error
+}
+
+// WithSleepTimerStore makes the player keep the sleep timer in the store
+// and continue the timer kept there on start.
+func WithSleepTimerStore(s SleepTimerStore) Option {
+	return func(p *Player) {
+		p.sleepStore = s
+	}
+}
+
+// SetSleepTimer replaces the sleep timer with the one pausing playback
+// at t.At or after t.Tracks tracks are played to the end. Time timers run
+// regardless of the state of the player and only pause playback if it is
+// playing when they fire. When a track timer fires, the following audio is
+// loaded but not played. ErrSleepTimerInPast is returned if t.At has passed.
+func (p *Player) SetSleepTimer(ctx context.Context, t models.SleepTimer) (*models.SleepTimer, error) {
+	if err := p.addCommand(ctx, commandMsg{command: SetSleepTimer, sleep: &t}); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SetSleepTimerAfter replaces the sleep timer with the one pausing playback after d.
+func (p *Player) SetSleepTimerAfter(ctx context.Context, d time.Duration) (*models.SleepTimer, error) {
+	t := models.SleepTimer{}
+	if err := p.addCommand(ctx, commandMsg{command: SetSleepTimer, sleep: &t, position: d}); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CancelSleepTimer removes the sleep timer if there is one.
+func (p *Player) CancelSleepTimer(ctx context.Context) error {
+	return p.addCommand(ctx, commandMsg{command: CancelSleepTimer})
+}
+
+// SleepTimer returns the sleep timer, nil if there is none.
+func (p *Player) SleepTimer() *models.SleepTimer {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	if p.sleep == nil {
+		return nil
+	}
+	t := *p.sleep
+	return &t
+}
+
+// setSleepTimer handles SetSleepTimer, a positive after is counted from now.
+func (p *Player) setSleepTimer(ctx context.Context, t *models.SleepTimer, after time.Duration, errCh chan error) {
+	now := p.clock.Now()
+	if after > 0 {
+		t.At = now.Add(after)
+	}
+	if !t.At.IsZero() && !t.At.After(now) {
+		errCh <- ErrSleepTimerInPast
+		return
+	}
+	p.logger.InfoContext(ctx, "sleep timer set", slog.Time("at", t.At), slog.Int("tracks", t.Tracks))
+	p.setSleep(ctx, t)
+	errCh <- nil
+}
+
+func (p *Player) cancelSleepTimer(ctx context.Context, errCh chan error) {
+	if p.sleep != nil {
+		p.logger.InfoContext(ctx, "sleep timer canceled")
+		p.setSleep(ctx, nil)
+	}
+	errCh <- nil
+}
+
+// setSleep replaces the sleep timer, arms its timer and saves it to the store.
+// The timer is replaced even if it fails to be saved.
+func (p *Player) setSleep(ctx context.Context, t *models.SleepTimer) {
+	if p.sleepTimer != nil {
+		p.sleepTimer.Stop()
+		p.sleepTimer = nil
+	}
+	if t != nil {
+		t = &models.SleepTimer{At: t.At, Tracks: t.Tracks}
+		if !t.At.IsZero() {
+			p.sleepTimer = p.clock.NewTimer(t.At.Sub(p.clock.Now()))
+		}
+	}
+
+	p.mtx.Lock()
+	p.sleep = t
+	p.mtx.Unlock()
+
+	if p.sleepStore != nil {
+		if err := p.sleepStore.SetSleepTimer(t); err != nil {
+			p.logger.ErrorContext(ctx, "save sleep timer error", slog.Any("error", err))
+		}
+	}
+}
+
+// sleepTimerC returns the channel of the time sleep timer, nil if there is none.
+func (p *Player) sleepTimerC() <-chan time.Time {
+	if p.sleepTimer == nil {
+		return nil
+	}
+	return p.sleepTimer.C()
+}
+
+// sleepTimerFired pauses playback when the time sleep timer fires.
+func (p *Player) sleepTimerFired(ctx context.Context) {
+	p.logger.InfoContext(ctx, "sleep timer fired", slog.String("state", p.state.String()))
+	p.setSleep(ctx, nil)
+	p.pause(ctx, make(chan error, 1))
+}
+
+// trackEnded counts the ended track by the track sleep timer
+// and reports whether the timer has fired.
+func (p *Player) trackEnded(ctx context.Context) bool {
+	if p.sleep == nil || p.sleep.Tracks == 0 {
+		return false
+	}
+	t := *p.sleep
+	t.Tracks--
+	if t.Tracks > 0 {
+		p.setSleep(ctx, &t)
+		return false
+	}
+	p.logger.InfoContext(ctx, "sleep timer fired at the end of track")
+	p.setSleep(ctx, nil)
+	return true
+}
@@ -1,5 +1,13 @@
 package player
 
+import (
+	"context"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type command uint8
 
 const (
@@ -7,25 +15,109 @@ const (
 	Pause
 	Next
 	Prev
+	Seek
+	Update
+	Delete
+	SetSleepTimer
+	CancelSleepTimer
 )
 
+func (c command) String() string {
+	switch c {
+	case Play:
+		return "play"
+	case Pause:
+		return "pause"
+	case Next:
+		return "next"
+	case Prev:
+		return "prev"
+	case Seek:
+		return "seek"
+	case Update:
+		return "update"
+	case Delete:
+		return "delete"
+	case SetSleepTimer:
+		return "set_sleep_timer"
+	case CancelSleepTimer:
+		return "cancel_sleep_timer"
+	default:
+		return "unknown"
+	}
+}
+
 type commandMsg struct {
-	command command
-	err     chan error
+	// ctx carries the trace of the request that sent the command.
+	ctx       context.Context
+	queueSpan trace.Span
+	command   command
+	// position is the argument of Seek and the time after which
+	// the sleep timer fires for SetSleepTimer.
+	position time.Duration
+	// audio is the argument of Update, it is replaced with the updated audio.
+	audio *models.Audio
+	// id and version are the arguments of Delete.
+	id      string
+	version uint64
+	// sleep is the argument of SetSleepTimer, it is replaced with the set timer.
+	sleep *models.SleepTimer
+	err   chan error
 }
 
-type state uint8
+// State is a state of the player.
+type State uint8
 
 const (
-	noActiveAudio state = iota
-	playing
-	paused
-	closed
+	NoActiveAudio State = iota
+	Playing
+	Paused
+	Closed
 )
 
+func (s State) String() string {
+	switch s {
+	case NoActiveAudio:
+		return "no_active_audio"
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	case Closed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is a snapshot of the player state.
+type Status struct {
+	State State
+	// Audio is the current audio, nil if there is no active audio.
+	Audio *models.Audio
+	// Position is the elapsed playback time of the current audio.
+	Position time.Duration
+}
+
+// playerSignals are sent by the loop to the audio goroutine. Play, pause, seek
+// and duration carry the time the loop sends them at, so that the playback
+// is timed from it rather than from the time the goroutine receives them.
 type playerSignals struct {
-	playCh  chan struct{}
-	pauseCh chan struct{}
-	endCh   chan struct{}
-	closeCh chan struct{}
+	playCh     chan time.Time
+	pauseCh    chan time.Time
+	endCh      chan struct{}
+	closeCh    chan struct{}
+	seekCh     chan seekSignal
+	durationCh chan durationSignal
+}
+
+type seekSignal struct {
+	position time.Duration
+	at       time.Time
+}
+
+// durationSignal changes the duration of the audio being played.
+type durationSignal struct {
+	duration time.Duration
+	at       time.Time
 }
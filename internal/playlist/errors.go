@@ -3,6 +3,11 @@ package playlist
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("audio not found")
-	ErrCurrentAudio = errors.New("invalid argument: this is the current audio")
+	ErrNotFound        = errors.New("audio not found")
+	ErrCurrentAudio    = errors.New("invalid argument: this is the current audio")
+	ErrVersionMismatch = errors.New("audio version mismatch")
+	ErrNotInTrash      = errors.New("audio not found in trash")
+	ErrNothingToUndo   = errors.New("nothing to undo")
+	ErrNothingToRedo   = errors.New("nothing to redo")
+	ErrHistoryDisabled = errors.New("history is disabled")
 )
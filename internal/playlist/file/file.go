@@ -1,12 +1,15 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/Karzoug/gocloudcamp/internal/models"
 	"github.com/Karzoug/gocloudcamp/internal/playlist/memory"
@@ -19,13 +22,31 @@ type filePlaylistConfig interface {
 
 type FilePlaylist struct {
 	memory.MemPlaylist
-	cfg filePlaylistConfig
+	cfg    filePlaylistConfig
+	logger *slog.Logger
+
+	sleepMtx sync.Mutex
+	sleep    *models.SleepTimer
+
+	// saveMtx serializes writes of the store file.
+	saveMtx sync.Mutex
+}
+
+// storeData is the content of the store file. Files written before
+// the sleep timer was saved contain only the array of audios.
+type storeData struct {
+	Audios     []models.Audio     `json:"audios"`
+	SleepTimer *models.SleepTimer `json:"sleep_timer,omitempty"`
 }
 
-func New(cfg filePlaylistConfig) (*FilePlaylist, error) {
+// New creates the playlist saved to the store file together with the sleep timer
+// on close and when the timer is set, opts configure the underlying in-memory playlist.
+// The trash is not saved.
+func New(cfg filePlaylistConfig, logger *slog.Logger, opts ...memory.Option) (*FilePlaylist, error) {
 	fp := &FilePlaylist{
-		MemPlaylist: *memory.New(),
+		MemPlaylist: *memory.New(logger, opts...),
 		cfg:         cfg,
+		logger:      logger,
 	}
 
 	if cfg.Restore() {
@@ -41,26 +62,67 @@ func (fp *FilePlaylist) Close() error {
 	return fp.saveData()
 }
 
-func (fp *FilePlaylist) saveData() error {
-	log.Printf("Save filelist to file: %s", fp.cfg.StoreFile())
+// Check checks that the store file can be opened for writing.
+func (fp *FilePlaylist) Check(_ context.Context) error {
+	file, err := os.OpenFile(fp.cfg.StoreFile(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open store file error: %w", err)
+	}
+	return file.Close()
+}
+
+// SleepTimer returns the sleep timer of the player restored from
+// the store file or set since then, nil if there is none.
+func (fp *FilePlaylist) SleepTimer() *models.SleepTimer {
+	fp.sleepMtx.Lock()
+	defer fp.sleepMtx.Unlock()
+
+	return fp.sleep
+}
+
+// SetSleepTimer sets the sleep timer of the player and saves it to the store
+// file at once with the playlist, so that the timer survives a crash.
+func (fp *FilePlaylist) SetSleepTimer(t *models.SleepTimer) error {
+	fp.sleepMtx.Lock()
+	fp.sleep = t
+	fp.sleepMtx.Unlock()
 
-	auds, err := fp.List(context.TODO())
+	return fp.saveData()
+}
+
+func (fp *FilePlaylist) saveData() (err error) {
+	fp.logger.Info("save playlist to file", slog.String("file", fp.cfg.StoreFile()))
+
+	defer func(start time.Time) {
+		saveDurationSeconds.Observe(time.Since(start).Seconds())
+		if err != nil {
+			saveFailuresTotal.Inc()
+		}
+	}(time.Now())
+
+	fp.saveMtx.Lock()
+	defer fp.saveMtx.Unlock()
+
+	auds, _, err := fp.List(context.TODO())
 	if err != nil {
 		return err
 	}
 
-	file, err := os.OpenFile(fp.cfg.StoreFile(), os.O_WRONLY|os.O_CREATE, 0644)
+	file, err := os.OpenFile(fp.cfg.StoreFile(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("open store file error: %w", err)
 	}
 	defer file.Close()
 
-	err = json.NewEncoder(file).Encode(auds)
+	err = json.NewEncoder(file).Encode(storeData{
+		Audios:     auds,
+		SleepTimer: fp.SleepTimer(),
+	})
 	return err
 }
 
 func (fp *FilePlaylist) restore() error {
-	log.Printf("Restore filelist from file: %s", fp.cfg.StoreFile())
+	fp.logger.Info("restore playlist from file", slog.String("file", fp.cfg.StoreFile()))
 
 	file, err := os.OpenFile(fp.cfg.StoreFile(), os.O_RDONLY|os.O_CREATE, 0644)
 	if err != nil {
@@ -68,12 +130,26 @@ func (fp *FilePlaylist) restore() error {
 	}
 	defer file.Close()
 
-	auds := make([]models.Audio, 0)
-	if err := json.NewDecoder(file).Decode(&auds); err != nil && err != io.EOF {
+	var raw json.RawMessage
+	if err := json.NewDecoder(file).Decode(&raw); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	var data storeData
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		err = json.Unmarshal(raw, &data.Audios)
+	} else {
+		err = json.Unmarshal(raw, &data)
+	}
+	if err != nil {
 		return err
 	}
-	if err := fp.MemPlaylist.SetAll(auds); err != nil {
+	if err := fp.MemPlaylist.SetAll(data.Audios); err != nil {
 		return err
 	}
+	fp.sleep = data.SleepTimer
 	return nil
 }
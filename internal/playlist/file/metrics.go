@@ -0,0 +1,17 @@
+package file
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	saveDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "playlist_file_save_duration_seconds",
+		Help: "Duration of saving the playlist to the store file.",
+	})
+	saveFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "playlist_file_save_failures_total",
+		Help: "Total number of failed savings of the playlist to the store file.",
+	})
+)
@@ -0,0 +1,388 @@
+package playlist
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+)
+
+// Op is an operation applied to the playlist by Undo or Redo.
+type Op uint8
+
+const (
+	OpDelete Op = iota + 1
+	OpRestore
+	OpUpdate
+	OpMove
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpDelete:
+		return "delete"
+	case OpRestore:
+		return "restore"
+	case OpUpdate:
+		return "update"
+	case OpMove:
+		return "move"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is the result of Undo or Redo.
+type Change struct {
+	Op Op
+	// Audio is the deleted, restored, updated or moved audio.
+	Audio *models.Audio
+	// Index is the position of the restored or moved audio.
+	Index int
+}
+
+// currentGuard is implemented by playlists that reject changes of the current
+// audio atomically, so that the player can not make the audio current between
+// the check and the change.
+type currentGuard interface {
+	// UpdateNotCurrent is Update returning ErrCurrentAudio for the current audio.
+	UpdateNotCurrent(ctx context.Context, a models.Audio) (*models.Audio, error)
+	// DeleteNotCurrent is Delete returning ErrCurrentAudio for the current audio.
+	DeleteNotCurrent(ctx context.Context, id string, version uint64) error
+}
+
+var errNoCurrentGuard = errors.New("playlist does not support changes excluding the current audio")
+
+// setter is implemented by playlists whose audios can be replaced at once.
+type setter interface {
+	SetAll(auds []models.Audio) error
+}
+
+// change is a recorded change of the playlist that can be undone and redone.
+// Both undo and redo update the versions the change expects, so that
+// it can be undone again after redo.
+type change interface {
+	undo(ctx context.Context, pl Playlist) (Change, error)
+	redo(ctx context.Context, pl Playlist) (Change, error)
+}
+
+type entry struct {
+	subject string
+	change  change
+}
+
+// History is a playlist recording changes made by Add, Update, Delete, Restore
+// and Move, so that they can be undone and redone by the principal
+// who made them or by anyone. It keeps at most depth latest changes.
+// Replacing all audios by SetAll is not recorded and clears the history.
+//
+// A change is undone by applying the inverse operation: a deleted audio is
+// restored from the trash, an added one is deleted to the trash and so on,
+// so deletions can be undone only while the trash keeps the audio.
+// A change that conflicts with later ones, e.g. an update of the audio that
+// has been updated since, is dropped from the history when it fails to be
+// undone or redone. Since the current audio may be updated and deleted only
+// by the player, undoing and redoing such changes of the current audio fail
+// with ErrCurrentAudio, the changes are kept to be tried again. The wrapped
+// playlist must implement UpdateNotCurrent and DeleteNotCurrent to undo and redo them.
+type History struct {
+	Playlist
+	depth   int
+	subject func(ctx context.Context) string
+	mtx     sync.Mutex
+	// done and undone are ordered by the time of the last application,
+	// the latest last.
+	done   []entry
+	undone []entry
+}
+
+// NewHistory returns a playlist recording changes of pl. subject returns
+// the principal making a change from the context of a call.
+func NewHistory(pl Playlist, depth int, subject func(ctx context.Context) string) *History {
+	return &History{
+		Playlist: pl,
+		depth:    depth,
+		subject:  subject,
+	}
+}
+
+// record must be called with h.mtx held.
+func (h *History) record(ctx context.Context, c change) {
+	subject := h.subject(ctx)
+	h.done = pushBounded(h.done, entry{subject: subject, change: c}, h.depth)
+	// a new change makes undone changes of the same principal impossible to redo
+	h.undone = slices.DeleteFunc(h.undone, func(e entry) bool { return e.subject == subject })
+}
+
+func pushBounded(entries []entry, e entry, depth int) []entry {
+	entries = append(entries, e)
+	if len(entries) > depth {
+		entries = slices.Delete(entries, 0, len(entries)-depth)
+	}
+	return entries
+}
+
+// Undo undoes the latest change made by the caller or,
+// if global is true, by anyone. ErrNothingToUndo is returned if there is none.
+func (h *History) Undo(ctx context.Context, global bool) (Change, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i := h.latest(ctx, h.done, global)
+	if i < 0 {
+		return Change{}, ErrNothingToUndo
+	}
+	return h.apply(ctx, &h.done, &h.undone, i, change.undo)
+}
+
+// Redo redoes the latest undone change made by the caller or,
+// if global is true, by anyone. ErrNothingToRedo is returned if there is none.
+func (h *History) Redo(ctx context.Context, global bool) (Change, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i := h.latest(ctx, h.undone, global)
+	if i < 0 {
+		return Change{}, ErrNothingToRedo
+	}
+	return h.apply(ctx, &h.undone, &h.done, i, change.redo)
+}
+
+// latest returns the index of the latest entry made by the caller
+// or of the latest entry at all if global is true, -1 if there is none.
+func (h *History) latest(ctx context.Context, entries []entry, global bool) int {
+	i := len(entries) - 1
+	if global {
+		return i
+	}
+	subject := h.subject(ctx)
+	for i >= 0 && entries[i].subject != subject {
+		i--
+	}
+	return i
+}
+
+// apply applies the entry i of from with fn and moves it to to.
+func (h *History) apply(ctx context.Context, from, to *[]entry, i int,
+	fn func(change, context.Context, Playlist) (Change, error)) (Change, error) {
+	e := (*from)[i]
+	res, err := fn(e.change, ctx, h.Playlist)
+	if err != nil {
+		if isConflict(err) {
+			*from = slices.Delete(*from, i, i+1)
+		}
+		return Change{}, err
+	}
+	*from = slices.Delete(*from, i, i+1)
+	*to = pushBounded(*to, e, h.depth)
+	return res, nil
+}
+
+// isConflict reports whether err means that the change
+// conflicts with later ones and can not be applied anymore.
+func isConflict(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrVersionMismatch) || errors.Is(err, ErrNotInTrash)
+}
+
+func (h *History) Add(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	res, err := h.Playlist.Add(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	h.record(ctx, &addChange{audio: *res})
+	return res, nil
+}
+
+func (h *History) Update(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	before, err := h.Playlist.Get(ctx, a.Id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := h.Playlist.Update(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	h.record(ctx, &updateChange{before: *before, after: *res, version: res.Version})
+	return res, nil
+}
+
+func (h *History) Delete(ctx context.Context, id string, version uint64) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	a, err := h.Playlist.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := h.Playlist.Delete(ctx, id, version); err != nil {
+		return err
+	}
+	h.record(ctx, &deleteChange{audio: *a})
+	return nil
+}
+
+func (h *History) Restore(ctx context.Context, id string) (*models.Audio, int, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	res, index, err := h.Playlist.Restore(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	// restoring is undone as adding is
+	h.record(ctx, &addChange{audio: *res})
+	return res, index, nil
+}
+
+func (h *History) Move(ctx context.Context, id string, index int) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	from, err := h.Playlist.Index(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := h.Playlist.Move(ctx, id, index); err != nil {
+		return err
+	}
+	to, err := h.Playlist.Index(ctx, id)
+	if err != nil {
+		return err
+	}
+	h.record(ctx, &moveChange{id: id, from: from, to: to})
+	return nil
+}
+
+// SetAll replaces all audios if the wrapped playlist supports it and clears the history.
+// The replacement is intentionally not undoable: it drops the current audio and
+// the trash, which its inverse could not bring back, and the recorded changes
+// refer to audios that are gone. It is meant for loading a stored playlist.
+func (h *History) SetAll(auds []models.Audio) error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	s, ok := h.Playlist.(setter)
+	if !ok {
+		return errors.New("playlist does not support replacing all audios")
+	}
+	if err := s.SetAll(auds); err != nil {
+		return err
+	}
+	h.done, h.undone = nil, nil
+	return nil
+}
+
+// Check delegates to the wrapped playlist if it is a Checker.
+func (h *History) Check(ctx context.Context) error {
+	if c, ok := h.Playlist.(Checker); ok {
+		return c.Check(ctx)
+	}
+	return nil
+}
+
+// addChange is an added or restored audio.
+type addChange struct {
+	audio models.Audio
+}
+
+func (c *addChange) undo(ctx context.Context, pl Playlist) (Change, error) {
+	return deleteNotCurrent(ctx, pl, c.audio)
+}
+
+func (c *addChange) redo(ctx context.Context, pl Playlist) (Change, error) {
+	a, index, err := pl.Restore(ctx, c.audio.Id)
+	if err != nil {
+		return Change{}, err
+	}
+	c.audio = *a
+	return Change{Op: OpRestore, Audio: a, Index: index}, nil
+}
+
+type deleteChange struct {
+	audio models.Audio
+}
+
+func (c *deleteChange) undo(ctx context.Context, pl Playlist) (Change, error) {
+	a, index, err := pl.Restore(ctx, c.audio.Id)
+	if err != nil {
+		return Change{}, err
+	}
+	c.audio = *a
+	return Change{Op: OpRestore, Audio: a, Index: index}, nil
+}
+
+func (c *deleteChange) redo(ctx context.Context, pl Playlist) (Change, error) {
+	return deleteNotCurrent(ctx, pl, c.audio)
+}
+
+type updateChange struct {
+	before, after models.Audio
+	// version is the version the audio has after the latest application.
+	version uint64
+}
+
+func (c *updateChange) undo(ctx context.Context, pl Playlist) (Change, error) {
+	return c.set(ctx, pl, c.before)
+}
+
+func (c *updateChange) redo(ctx context.Context, pl Playlist) (Change, error) {
+	return c.set(ctx, pl, c.after)
+}
+
+func (c *updateChange) set(ctx context.Context, pl Playlist, a models.Audio) (Change, error) {
+	g, ok := pl.(currentGuard)
+	if !ok {
+		return Change{}, errNoCurrentGuard
+	}
+	a.Version = c.version
+	res, err := g.UpdateNotCurrent(ctx, a)
+	if err != nil {
+		return Change{}, err
+	}
+	c.version = res.Version
+	return Change{Op: OpUpdate, Audio: res}, nil
+}
+
+// deleteNotCurrent deletes the audio of the expected version unless it is the current one.
+func deleteNotCurrent(ctx context.Context, pl Playlist, a models.Audio) (Change, error) {
+	g, ok := pl.(currentGuard)
+	if !ok {
+		return Change{}, errNoCurrentGuard
+	}
+	if err := g.DeleteNotCurrent(ctx, a.Id, a.Version); err != nil {
+		return Change{}, err
+	}
+	return Change{Op: OpDelete, Audio: &a}, nil
+}
+
+type moveChange struct {
+	id       string
+	from, to int
+}
+
+func (c *moveChange) undo(ctx context.Context, pl Playlist) (Change, error) {
+	return move(ctx, pl, c.id, c.from)
+}
+
+func (c *moveChange) redo(ctx context.Context, pl Playlist) (Change, error) {
+	return move(ctx, pl, c.id, c.to)
+}
+
+func move(ctx context.Context, pl Playlist, id string, index int) (Change, error) {
+	if err := pl.Move(ctx, id, index); err != nil {
+		return Change{}, err
+	}
+	a, err := pl.Get(ctx, id)
+	if err != nil {
+		return Change{}, err
+	}
+	return Change{Op: OpMove, Audio: a, Index: index}, nil
+}
@@ -0,0 +1,441 @@
+package playlist
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+)
+
+// fakePlaylist is a slice of audios with the trash keeping deleted audios
+// forever and restoring them to their former index.
+type fakePlaylist struct {
+	audios  []models.Audio
+	trash   map[string]models.DeletedAudio
+	current string
+	lastId  int
+}
+
+func newFakePlaylist() *fakePlaylist {
+	return &fakePlaylist{trash: make(map[string]models.DeletedAudio)}
+}
+
+func (p *fakePlaylist) find(id string) int {
+	return slices.IndexFunc(p.audios, func(a models.Audio) bool { return a.Id == id })
+}
+
+func (p *fakePlaylist) at(i int) *models.Audio {
+	if i < 0 || i >= len(p.audios) {
+		return nil
+	}
+	a := p.audios[i]
+	return &a
+}
+
+func (p *fakePlaylist) setCurrent(i int) *models.Audio {
+	a := p.at(i)
+	p.current = ""
+	if a != nil {
+		p.current = a.Id
+	}
+	return a
+}
+
+func (p *fakePlaylist) Current(context.Context) *models.Audio {
+	return p.at(p.find(p.current))
+}
+
+func (p *fakePlaylist) CurrentToFront(context.Context) *models.Audio {
+	return p.setCurrent(0)
+}
+
+func (p *fakePlaylist) CurrentToNext(context.Context) *models.Audio {
+	return p.setCurrent(p.find(p.current) + 1)
+}
+
+func (p *fakePlaylist) CurrentToPrev(context.Context) *models.Audio {
+	return p.setCurrent(p.find(p.current) - 1)
+}
+
+func (p *fakePlaylist) Front(context.Context) *models.Audio {
+	return p.at(0)
+}
+
+func (p *fakePlaylist) Back(context.Context) *models.Audio {
+	return p.at(len(p.audios) - 1)
+}
+
+func (p *fakePlaylist) Add(_ context.Context, a models.Audio) (*models.Audio, error) {
+	p.lastId++
+	a.Id = strconv.Itoa(p.lastId)
+	a.Version = 1
+	p.audios = append(p.audios, a)
+	return &a, nil
+}
+
+func (p *fakePlaylist) Get(_ context.Context, id string) (*models.Audio, error) {
+	i := p.find(id)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	return p.at(i), nil
+}
+
+func (p *fakePlaylist) Index(_ context.Context, id string) (int, error) {
+	i := p.find(id)
+	if i < 0 {
+		return 0, ErrNotFound
+	}
+	return i, nil
+}
+
+func (p *fakePlaylist) Update(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	i := p.find(a.Id)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	if a.Version != 0 && a.Version != p.audios[i].Version {
+		return nil, ErrVersionMismatch
+	}
+	a.Version = p.audios[i].Version + 1
+	p.audios[i] = a
+	return &a, nil
+}
+
+func (p *fakePlaylist) UpdateNotCurrent(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	if a.Id == p.current {
+		return nil, ErrCurrentAudio
+	}
+	return p.Update(ctx, a)
+}
+
+func (p *fakePlaylist) Delete(_ context.Context, id string, version uint64) error {
+	i := p.find(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if version != 0 && version != p.audios[i].Version {
+		return ErrVersionMismatch
+	}
+	if id == p.current {
+		p.setCurrent(i + 1)
+	}
+	p.trash[id] = models.DeletedAudio{Audio: p.audios[i], Index: i}
+	p.audios = slices.Delete(p.audios, i, i+1)
+	return nil
+}
+
+func (p *fakePlaylist) DeleteNotCurrent(ctx context.Context, id string, version uint64) error {
+	if id == p.current {
+		return ErrCurrentAudio
+	}
+	return p.Delete(ctx, id, version)
+}
+
+func (p *fakePlaylist) Move(_ context.Context, id string, index int) error {
+	i := p.find(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	a := p.audios[i]
+	p.audios = slices.Delete(p.audios, i, i+1)
+	p.audios = slices.Insert(p.audios, min(index, len(p.audios)), a)
+	return nil
+}
+
+func (p *fakePlaylist) Len(context.Context) int {
+	return len(p.audios)
+}
+
+func (p *fakePlaylist) List(context.Context) ([]models.Audio, uint64, error) {
+	return slices.Clone(p.audios), 0, nil
+}
+
+func (p *fakePlaylist) ListTrash(context.Context) ([]models.DeletedAudio, error) {
+	return nil, nil
+}
+
+func (p *fakePlaylist) Restore(_ context.Context, id string) (*models.Audio, int, error) {
+	d, ok := p.trash[id]
+	if !ok {
+		return nil, 0, ErrNotInTrash
+	}
+	delete(p.trash, id)
+	index := min(d.Index, len(p.audios))
+	p.audios = slices.Insert(p.audios, index, d.Audio)
+	return &d.Audio, index, nil
+}
+
+func (p *fakePlaylist) PurgeTrash(context.Context, ...string) (int, error) {
+	return 0, nil
+}
+
+func (p *fakePlaylist) SetAll(auds []models.Audio) error {
+	p.audios = slices.Clone(auds)
+	p.trash = make(map[string]models.DeletedAudio)
+	p.current = ""
+	return nil
+}
+
+func (p *fakePlaylist) Close() error {
+	return nil
+}
+
+type subjectKey struct{}
+
+func testSubject(ctx context.Context) string {
+	s, _ := ctx.Value(subjectKey{}).(string)
+	return s
+}
+
+// historyStep is a call to History made by the subject.
+type historyStep struct {
+	subject string
+	// do is "add", "update", "delete", "move", "set_all", "current", "undo" or "redo",
+	// "current" makes the audio current as the player does, "set_all" replaces
+	// the playlist with the audio of the name.
+	do string
+	// name is the name the audio is added with, updated audios get "*" appended.
+	name   string
+	index  int
+	global bool
+
+	wantOp  Op
+	wantErr error
+}
+
+func TestHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int
+		steps []historyStep
+		want  []string
+	}{
+		{
+			name: "undo and redo add",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "redo", wantOp: OpRestore},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "set all clears the history",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "bob", do: "add", name: "b"},
+				{subject: "bob", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "set_all", name: "c"},
+				{subject: "alice", do: "undo", global: true, wantErr: ErrNothingToUndo},
+				{subject: "bob", do: "redo", wantErr: ErrNothingToRedo},
+			},
+			want: []string{"c"},
+		},
+		{
+			name: "changes after set all",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "set_all", name: "c"},
+				{subject: "alice", do: "add", name: "d"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "undo", wantErr: ErrNothingToUndo},
+			},
+			want: []string{"c"},
+		},
+		{
+			name: "undo in reverse order",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "update", name: "a"},
+				{subject: "alice", do: "move", name: "a", index: 1},
+				{subject: "alice", do: "delete", name: "b"},
+				{subject: "alice", do: "undo", wantOp: OpRestore},
+				{subject: "alice", do: "undo", wantOp: OpMove},
+				{subject: "alice", do: "undo", wantOp: OpUpdate},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "redo in reverse order of undo",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "move", name: "b", index: 0},
+				{subject: "alice", do: "update", name: "a"},
+				{subject: "alice", do: "undo", wantOp: OpUpdate},
+				{subject: "alice", do: "undo", wantOp: OpMove},
+				{subject: "alice", do: "redo", wantOp: OpMove},
+				{subject: "alice", do: "redo", wantOp: OpUpdate},
+				{subject: "alice", do: "redo", wantErr: ErrNothingToRedo},
+			},
+			want: []string{"b", "a*"},
+		},
+		{
+			name: "own changes only",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "bob", do: "add", name: "b"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "undo", wantErr: ErrNothingToUndo},
+				{subject: "bob", do: "redo", wantErr: ErrNothingToRedo},
+			},
+			want: []string{"b"},
+		},
+		{
+			name: "global",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "bob", do: "add", name: "b"},
+				{subject: "alice", do: "undo", global: true, wantOp: OpDelete},
+				{subject: "alice", do: "undo", global: true, wantOp: OpDelete},
+				{subject: "bob", do: "redo", global: true, wantOp: OpRestore},
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "nothing to undo",
+			steps: []historyStep{
+				{subject: "alice", do: "undo", wantErr: ErrNothingToUndo},
+				{subject: "alice", do: "redo", wantErr: ErrNothingToRedo},
+			},
+		},
+		{
+			name:  "depth limit",
+			depth: 2,
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "add", name: "c"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "undo", wantErr: ErrNothingToUndo},
+			},
+			want: []string{"a"},
+		},
+		{
+			name:  "depth limit of undone",
+			depth: 2,
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "redo", wantOp: OpRestore},
+				{subject: "alice", do: "redo", wantOp: OpRestore},
+				{subject: "alice", do: "redo", wantErr: ErrNothingToRedo},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "new change invalidates redo",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "redo", wantErr: ErrNothingToRedo},
+			},
+			want: []string{"b"},
+		},
+		{
+			name: "change of another subject keeps redo",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "bob", do: "add", name: "b"},
+				{subject: "alice", do: "redo", wantOp: OpRestore},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "conflicting change is dropped",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "update", name: "a"},
+				{subject: "bob", do: "update", name: "a"},
+				{subject: "alice", do: "undo", wantErr: ErrVersionMismatch},
+				{subject: "alice", do: "undo", wantErr: ErrVersionMismatch},
+				{subject: "alice", do: "undo", wantErr: ErrNothingToUndo},
+			},
+			want: []string{"a**"},
+		},
+		{
+			name: "current audio is kept",
+			steps: []historyStep{
+				{subject: "alice", do: "add", name: "a"},
+				{subject: "alice", do: "add", name: "b"},
+				{subject: "alice", do: "current", name: "b"},
+				{subject: "alice", do: "undo", wantErr: ErrCurrentAudio},
+				{subject: "alice", do: "current", name: "a"},
+				{subject: "alice", do: "undo", wantOp: OpDelete},
+				{subject: "alice", do: "undo", wantErr: ErrCurrentAudio},
+			},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth := tt.depth
+			if depth == 0 {
+				depth = 100
+			}
+			pl := newFakePlaylist()
+			h := NewHistory(pl, depth, testSubject)
+			ids := make(map[string]string)
+			for i, s := range tt.steps {
+				ctx := context.WithValue(context.Background(), subjectKey{}, s.subject)
+				var (
+					c   Change
+					err error
+				)
+				switch s.do {
+				case "add":
+					var a *models.Audio
+					a, err = h.Add(ctx, models.Audio{Name: s.name})
+					if err == nil {
+						ids[s.name] = a.Id
+					}
+				case "update":
+					var a *models.Audio
+					a, err = h.Get(ctx, ids[s.name])
+					if err == nil {
+						a.Name += "*"
+						_, err = h.Update(ctx, *a)
+					}
+				case "delete":
+					err = h.Delete(ctx, ids[s.name], 0)
+				case "move":
+					err = h.Move(ctx, ids[s.name], s.index)
+				case "set_all":
+					err = h.SetAll([]models.Audio{{Id: "set-" + s.name, Name: s.name, Version: 1}})
+				case "current":
+					pl.current = ids[s.name]
+				case "undo":
+					c, err = h.Undo(ctx, s.global)
+				case "redo":
+					c, err = h.Redo(ctx, s.global)
+				}
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("step %d (%s %s) error = %v, want %v", i, s.do, s.name, err, s.wantErr)
+				}
+				if c.Op != s.wantOp {
+					t.Fatalf("step %d (%s) op = %s, want %s", i, s.do, c.Op, s.wantOp)
+				}
+			}
+
+			var got []string
+			for _, a := range pl.audios {
+				got = append(got, a.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("playlist = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
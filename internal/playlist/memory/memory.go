@@ -1,192 +1,291 @@
 package memory
 
 import (
-	"container/list"
 	"context"
-	"log"
+	"log/slog"
 	"sync"
+	"time"
 
+	"github.com/Karzoug/gocloudcamp/internal/logging"
 	"github.com/Karzoug/gocloudcamp/internal/models"
 	"github.com/Karzoug/gocloudcamp/internal/playlist"
+	"github.com/Karzoug/gocloudcamp/pkg/clock"
 	"github.com/rs/xid"
 )
 
+// MemPlaylist keeps audios in memory. Audios are indexed by id and ordered
+// by a balanced tree, so that access by id takes O(1) time and access by
+// position, insertion, removal and moving take O(log n) time.
+// Deleted audios are kept in the trash for the trash retention.
 type MemPlaylist struct {
-	list    *list.List
-	current *list.Element
-	mtx     sync.RWMutex
+	logger         *slog.Logger
+	order          tree
+	byId           map[string]*node
+	current        *node
+	revision       uint64
+	trash          []trashItem
+	trashRetention time.Duration
+	clock          clock.Clock
+	mtx            sync.RWMutex
 }
 
-func New() *MemPlaylist {
+// Option configures MemPlaylist.
+type Option func(*MemPlaylist)
+
+// WithTrashRetention makes deleted audios to be kept in the trash for d,
+// zero value (the default) deletes audios permanently.
+func WithTrashRetention(d time.Duration) Option {
+	return func(p *MemPlaylist) {
+		p.trashRetention = d
+	}
+}
+
+// WithClock replaces the system clock measuring the trash retention.
+func WithClock(c clock.Clock) Option {
+	return func(p *MemPlaylist) {
+		p.clock = c
+	}
+}
+
+func New(logger *slog.Logger, opts ...Option) *MemPlaylist {
 	p := MemPlaylist{
-		list: list.New(),
-		mtx:  sync.RWMutex{},
+		logger: logger,
+		byId:   make(map[string]*node),
+		clock:  clock.New(),
+		mtx:    sync.RWMutex{},
+	}
+	for _, opt := range opts {
+		opt(&p)
 	}
-	p.current = p.list.Front()
 	return &p
 }
 
-func (p *MemPlaylist) Current() *models.Audio {
-	p.mtx.RLock()
-	defer p.mtx.RUnlock()
-
-	if p.current == nil {
+// audioOf returns a copy of the audio of n, nil if n is nil.
+func audioOf(n *node) *models.Audio {
+	if n == nil {
 		return nil
 	}
-	audio, _ := p.current.Value.(models.Audio)
+	audio := n.audio
 	return &audio
 }
 
-func (p *MemPlaylist) CurrentToFront() *models.Audio {
+func (p *MemPlaylist) Current(_ context.Context) *models.Audio {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	return audioOf(p.current)
+}
+
+func (p *MemPlaylist) CurrentToFront(_ context.Context) *models.Audio {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
-	p.current = p.list.Front()
-	if p.current == nil {
-		return nil
-	}
-	audio, _ := p.current.Value.(models.Audio)
-	return &audio
+	p.current = p.order.front()
+	return audioOf(p.current)
 }
 
-func (p *MemPlaylist) CurrentToNext() *models.Audio {
+func (p *MemPlaylist) CurrentToNext(_ context.Context) *models.Audio {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
 	if p.current == nil {
 		return nil
 	}
-	p.current = p.current.Next()
-	if p.current == nil {
-		return nil
-	}
-	audio, _ := p.current.Value.(models.Audio)
-	return &audio
+	p.current = next(p.current)
+	return audioOf(p.current)
 }
 
-func (p *MemPlaylist) CurrentToPrev() *models.Audio {
+func (p *MemPlaylist) CurrentToPrev(_ context.Context) *models.Audio {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
 	if p.current == nil {
 		return nil
 	}
-	p.current = p.current.Prev()
-	if p.current == nil {
-		return nil
-	}
-	audio, _ := p.current.Value.(models.Audio)
-	return &audio
+	p.current = prev(p.current)
+	return audioOf(p.current)
 }
 
-func (p *MemPlaylist) Front() *models.Audio {
+func (p *MemPlaylist) Front(_ context.Context) *models.Audio {
 	p.mtx.RLock()
 	defer p.mtx.RUnlock()
 
-	if p.list.Front() == nil {
-		return nil
-	}
-	audio, _ := p.list.Front().Value.(models.Audio)
-	return &audio
+	return audioOf(p.order.front())
 }
 
-func (p *MemPlaylist) Back() *models.Audio {
+func (p *MemPlaylist) Back(_ context.Context) *models.Audio {
 	p.mtx.RLock()
 	defer p.mtx.RUnlock()
 
-	if p.list.Back() == nil {
-		return nil
-	}
-	audio, _ := p.list.Back().Value.(models.Audio)
-	return &audio
+	return audioOf(p.order.back())
 }
 
-func (p *MemPlaylist) Add(_ context.Context, a models.Audio) (*models.Audio, error) {
-	log.Printf("Add new audio: %s", a.Name)
+func (p *MemPlaylist) Add(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	p.logger.DebugContext(ctx, "add audio", slog.Any("name", logging.UserText(a.Name)))
 
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
 	a.Id = xid.New().String()
-	p.list.PushBack(a)
+	a.Version = 1
+	n := p.order.newNode(a)
+	p.order.pushBack(n)
+	p.byId[a.Id] = n
+	p.revision++
 
 	return &a, nil
 }
 
-func (p *MemPlaylist) Get(_ context.Context, id string) (*models.Audio, error) {
-	log.Printf("Get audio with id: %s", id)
+func (p *MemPlaylist) Get(ctx context.Context, id string) (*models.Audio, error) {
+	p.logger.DebugContext(ctx, "get audio", slog.String("id", id))
 
 	p.mtx.RLock()
 	defer p.mtx.RUnlock()
 
-	for e := p.list.Front(); e != nil; e = e.Next() {
-		if v := e.Value.(models.Audio); v.Id == id {
-			return &v, nil
-		}
+	n, ok := p.byId[id]
+	if !ok {
+		return nil, playlist.ErrNotFound
 	}
-	return nil, playlist.ErrNotFound
+	return audioOf(n), nil
 }
 
-func (p *MemPlaylist) Update(_ context.Context, a models.Audio) (*models.Audio, error) {
-	log.Printf("Update audio with id: %s", a.Id)
+func (p *MemPlaylist) Index(ctx context.Context, id string) (int, error) {
+	p.logger.DebugContext(ctx, "get audio index", slog.String("id", id))
+
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	n, ok := p.byId[id]
+	if !ok {
+		return 0, playlist.ErrNotFound
+	}
+	return p.order.index(n), nil
+}
+
+func (p *MemPlaylist) Len(_ context.Context) int {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	return p.order.len()
+}
+
+func (p *MemPlaylist) Update(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	return p.update(ctx, a, true)
+}
+
+func (p *MemPlaylist) UpdateNotCurrent(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	return p.update(ctx, a, false)
+}
+
+// update updates the audio, the current one only if current is true.
+func (p *MemPlaylist) update(ctx context.Context, a models.Audio, current bool) (*models.Audio, error) {
+	p.logger.DebugContext(ctx, "update audio", slog.String("id", a.Id), slog.Any("name", logging.UserText(a.Name)))
 
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
-	if p.current != nil && p.current.Value.(models.Audio).Id == a.Id {
+	n, ok := p.byId[a.Id]
+	if !ok {
+		return nil, playlist.ErrNotFound
+	}
+	if !current && n == p.current {
 		return nil, playlist.ErrCurrentAudio
 	}
-
-	for e := p.list.Front(); e != nil; e = e.Next() {
-		if v := e.Value.(models.Audio); v.Id == a.Id {
-			e.Value = a
-			v = e.Value.(models.Audio)
-			return &v, nil
-		}
+	if a.Version != 0 && a.Version != n.audio.Version {
+		return nil, playlist.ErrVersionMismatch
 	}
-	return nil, playlist.ErrNotFound
+	a.Version = n.audio.Version + 1
+	n.audio = a
+	p.revision++
+	return &a, nil
 }
 
-func (p *MemPlaylist) Delete(_ context.Context, id string) error {
-	log.Printf("Delete audio with id: %s", id)
+func (p *MemPlaylist) Delete(ctx context.Context, id string, version uint64) error {
+	return p.delete(ctx, id, version, true)
+}
+
+func (p *MemPlaylist) DeleteNotCurrent(ctx context.Context, id string, version uint64) error {
+	return p.delete(ctx, id, version, false)
+}
+
+// delete deletes the audio, the current one only if current is true.
+func (p *MemPlaylist) delete(ctx context.Context, id string, version uint64, current bool) error {
+	p.logger.DebugContext(ctx, "delete audio", slog.String("id", id))
 
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
-	if p.current != nil && p.current.Value.(models.Audio).Id == id {
+	n, ok := p.byId[id]
+	if !ok {
+		return playlist.ErrNotFound
+	}
+	if !current && n == p.current {
 		return playlist.ErrCurrentAudio
 	}
+	if version != 0 && version != n.audio.Version {
+		return playlist.ErrVersionMismatch
+	}
+	if n == p.current {
+		p.current = next(n)
+	}
+	if p.trashRetention > 0 {
+		p.toTrash(n)
+	}
+	p.order.remove(n)
+	delete(p.byId, id)
+	p.revision++
+	return nil
+}
 
-	for e := p.list.Front(); e != nil; e = e.Next() {
-		if v := e.Value.(models.Audio); v.Id == id {
-			p.list.Remove(e)
-			return nil
-		}
+func (p *MemPlaylist) Move(ctx context.Context, id string, index int) error {
+	p.logger.DebugContext(ctx, "move audio", slog.String("id", id), slog.Int("index", index))
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	n, ok := p.byId[id]
+	if !ok {
+		return playlist.ErrNotFound
 	}
+	// index is counted among the other audios
+	p.order.remove(n)
+	p.order.insert(n, index)
+	p.revision++
 	return nil
 }
 
-func (p *MemPlaylist) List(_ context.Context) ([]models.Audio, error) {
-	log.Println("Get audio list")
+func (p *MemPlaylist) List(ctx context.Context) ([]models.Audio, uint64, error) {
+	p.logger.DebugContext(ctx, "list audios")
 
 	p.mtx.RLock()
 	defer p.mtx.RUnlock()
 
-	slice := make([]models.Audio, 0, p.list.Len())
-	for e := p.list.Front(); e != nil; e = e.Next() {
-		slice = append(slice, e.Value.(models.Audio))
+	slice := make([]models.Audio, 0, p.order.len())
+	for n := p.order.front(); n != nil; n = next(n) {
+		slice = append(slice, n.audio)
 	}
-	return slice, nil
+	return slice, p.revision, nil
 }
 
+// SetAll replaces all audios and empties the trash,
+// there is no current audio after it.
 func (p *MemPlaylist) SetAll(auds []models.Audio) error {
 	p.mtx.Lock()
 	defer p.mtx.Unlock()
 
-	p.list.Init()
+	p.order = tree{}
+	p.byId = make(map[string]*node, len(auds))
+	p.current = nil
+	p.trash = nil
 	for _, a := range auds {
-		p.list.PushBack(a)
+		if a.Version == 0 {
+			a.Version = 1
+		}
+		n := p.order.newNode(a)
+		p.order.pushBack(n)
+		p.byId[a.Id] = n
 	}
+	p.revision++
 
 	return nil
 }
@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+)
+
+var benchSizes = []int{10_000, 100_000}
+
+// newBenchPlaylist returns a playlist of n audios and their ids.
+func newBenchPlaylist(b *testing.B, n int) (*MemPlaylist, []string) {
+	b.Helper()
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		a, err := p.Add(context.Background(), models.Audio{Name: fmt.Sprintf("audio %d", i), Duration: time.Minute})
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids = append(ids, a.Id)
+	}
+	return p, ids
+}
+
+func benchSizesRun(b *testing.B, fn func(b *testing.B, p *MemPlaylist, ids []string)) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			p, ids := newBenchPlaylist(b, n)
+			b.ResetTimer()
+			fn(b, p, ids)
+		})
+	}
+}
+
+func BenchmarkMemPlaylist_Add(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, _ []string) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := p.Add(ctx, models.Audio{Name: "audio", Duration: time.Minute}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemPlaylist_Get(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, ids []string) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < b.N; i++ {
+			if _, err := p.Get(ctx, ids[rnd.Intn(len(ids))]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemPlaylist_Update(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, ids []string) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < b.N; i++ {
+			a := models.Audio{Id: ids[rnd.Intn(len(ids))], Name: "updated", Duration: time.Minute}
+			if _, err := p.Update(ctx, a); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMemPlaylist_DeleteAdd deletes a random audio and adds a new one
+// to keep the size of the playlist.
+func BenchmarkMemPlaylist_DeleteAdd(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, ids []string) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < b.N; i++ {
+			j := rnd.Intn(len(ids))
+			if err := p.Delete(ctx, ids[j], 0); err != nil {
+				b.Fatal(err)
+			}
+			a, err := p.Add(ctx, models.Audio{Name: "audio", Duration: time.Minute})
+			if err != nil {
+				b.Fatal(err)
+			}
+			ids[j] = a.Id
+		}
+	})
+}
+
+func BenchmarkMemPlaylist_Move(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, ids []string) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < b.N; i++ {
+			if err := p.Move(ctx, ids[rnd.Intn(len(ids))], rnd.Intn(len(ids))); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMemPlaylist_MoveEnds moves audios to the front and past the end
+// of the playlist, the cases of moving with the largest distance.
+func BenchmarkMemPlaylist_MoveEnds(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, ids []string) {
+		ctx := context.Background()
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < b.N; i++ {
+			index := 0
+			if i%2 == 1 {
+				index = len(ids)
+			}
+			if err := p.Move(ctx, ids[rnd.Intn(len(ids))], index); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMemPlaylist_GetMissing looks up ids that are not in the playlist.
+func BenchmarkMemPlaylist_GetMissing(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, _ []string) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, err := p.Get(ctx, "cn0000000000000000a0"); err == nil {
+				b.Fatal("audio is found")
+			}
+		}
+	})
+}
+
+func BenchmarkMemPlaylist_List(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, _ []string) {
+		ctx := context.Background()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := p.List(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemPlaylist_CurrentToNext(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, p *MemPlaylist, _ []string) {
+		ctx := context.Background()
+		p.CurrentToFront(ctx)
+		for i := 0; i < b.N; i++ {
+			if p.CurrentToNext(ctx) == nil {
+				p.CurrentToFront(ctx)
+			}
+		}
+	})
+}
+
+func TestMemPlaylist_NotCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		delete  bool
+		current bool
+		wantErr error
+	}{
+		{name: "update current", current: true, wantErr: playlist.ErrCurrentAudio},
+		{name: "update other"},
+		{name: "delete current", delete: true, current: true, wantErr: playlist.ErrCurrentAudio},
+		{name: "delete other", delete: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, ids := newTestPlaylist(t, &fakeClock{now: time.Now()}, "a", "b")
+			p.CurrentToFront(ctx)
+			if tt.current {
+				p.CurrentToNext(ctx)
+			}
+
+			var err error
+			if tt.delete {
+				err = p.DeleteNotCurrent(ctx, ids["b"], 0)
+			} else {
+				_, err = p.UpdateNotCurrent(ctx, models.Audio{Id: ids["b"], Name: "b*", Duration: time.Minute})
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				return
+			}
+			if a, err := p.Get(ctx, ids["b"]); err != nil || a.Name != "b" || a.Version != 1 {
+				t.Errorf("current audio is changed: %+v, %v", a, err)
+			}
+		})
+	}
+}
+
+func TestMemPlaylist_DeleteCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		delete  string
+		// want is the current audio after the deletion, empty if there is none
+		want string
+	}{
+		{name: "first", current: "a", delete: "a", want: "b"},
+		{name: "middle", current: "b", delete: "b", want: "c"},
+		{name: "last", current: "c", delete: "c"},
+		{name: "other", current: "b", delete: "a", want: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, ids := newTestPlaylist(t, &fakeClock{now: time.Now()}, "a", "b", "c")
+			for a := p.CurrentToFront(ctx); a.Name != tt.current; a = p.CurrentToNext(ctx) {
+			}
+
+			if err := p.Delete(ctx, ids[tt.delete], 0); err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if a := p.Current(ctx); a != nil {
+				got = a.Name
+			}
+			if got != tt.want {
+				t.Errorf("current = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+)
+
+// trashItem is a deleted audio kept in the trash.
+type trashItem struct {
+	models.DeletedAudio
+	// prevId and nextId are the ids of the audios preceding and following
+	// the deleted one in the playlist, empty if there were none.
+	prevId, nextId string
+}
+
+// toTrash puts the audio of n, which is about to be removed, to the trash.
+// Trash items are ordered by deletion time, so do their expiration times.
+func (p *MemPlaylist) toTrash(n *node) {
+	now := p.clock.Now()
+	p.pruneTrash(now)
+
+	item := trashItem{
+		DeletedAudio: models.DeletedAudio{
+			Audio:     n.audio,
+			Index:     p.order.index(n),
+			DeletedAt: now,
+			ExpiresAt: now.Add(p.trashRetention),
+		},
+	}
+	if pn := prev(n); pn != nil {
+		item.prevId = pn.audio.Id
+	}
+	if nn := next(n); nn != nil {
+		item.nextId = nn.audio.Id
+	}
+	p.trash = append(p.trash, item)
+}
+
+// pruneTrash removes expired items from the trash.
+func (p *MemPlaylist) pruneTrash(now time.Time) {
+	i := 0
+	for i < len(p.trash) && !p.trash[i].ExpiresAt.After(now) {
+		i++
+	}
+	if i > 0 {
+		p.trash = slices.Delete(p.trash, 0, i)
+	}
+}
+
+func (p *MemPlaylist) ListTrash(ctx context.Context) ([]models.DeletedAudio, error) {
+	p.logger.DebugContext(ctx, "list trash")
+
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	now := p.clock.Now()
+	slice := make([]models.DeletedAudio, 0, len(p.trash))
+	for i := len(p.trash) - 1; i >= 0 && p.trash[i].ExpiresAt.After(now); i-- {
+		slice = append(slice, p.trash[i].DeletedAudio)
+	}
+	return slice, nil
+}
+
+// Restore inserts the audio between its former neighbours if they are still
+// adjacent, so that restoring audios in reverse order of deletion recovers
+// the playlist exactly. Otherwise it is inserted after the former preceding
+// audio, before the former following one or at its former index,
+// whichever is possible first.
+func (p *MemPlaylist) Restore(ctx context.Context, id string) (*models.Audio, int, error) {
+	p.logger.DebugContext(ctx, "restore audio", slog.String("id", id))
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.pruneTrash(p.clock.Now())
+	i := slices.IndexFunc(p.trash, func(item trashItem) bool { return item.Id == id })
+	if i < 0 {
+		return nil, 0, playlist.ErrNotInTrash
+	}
+	item := p.trash[i]
+	p.trash = slices.Delete(p.trash, i, i+1)
+
+	index := p.restoreIndex(item)
+	n := p.order.newNode(item.Audio)
+	p.order.insert(n, index)
+	p.byId[id] = n
+	p.revision++
+
+	return audioOf(n), index, nil
+}
+
+func (p *MemPlaylist) restoreIndex(item trashItem) int {
+	pn, hasPrev := p.byId[item.prevId]
+	nn, hasNext := p.byId[item.nextId]
+	switch {
+	case hasNext && (hasPrev && next(pn) == nn || item.prevId == "" && prev(nn) == nil):
+		// the former neighbours are still adjacent
+		return p.order.index(nn)
+	case hasPrev:
+		return p.order.index(pn) + 1
+	case hasNext:
+		return p.order.index(nn)
+	default:
+		return min(item.Index, p.order.len())
+	}
+}
+
+func (p *MemPlaylist) PurgeTrash(ctx context.Context, ids ...string) (int, error) {
+	p.logger.DebugContext(ctx, "purge trash", slog.Int("ids", len(ids)))
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.pruneTrash(p.clock.Now())
+	if len(ids) == 0 {
+		n := len(p.trash)
+		p.trash = nil
+		return n, nil
+	}
+
+	before := len(p.trash)
+	p.trash = slices.DeleteFunc(p.trash, func(item trashItem) bool {
+		return slices.Contains(ids, item.Id)
+	})
+	return before - len(p.trash), nil
+}
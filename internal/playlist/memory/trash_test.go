@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+	"github.com/Karzoug/gocloudcamp/pkg/clock"
+)
+
+const testRetention = time.Hour
+
+// fakeClock shows the time set by advance, it has no timers.
+type fakeClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(time.Duration) clock.Timer {
+	panic("timers are not used by the playlist")
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestPlaylist returns a playlist of audios with the names
+// and the ids of the audios by name.
+func newTestPlaylist(t *testing.T, c clock.Clock, names ...string) (*MemPlaylist, map[string]string) {
+	t.Helper()
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)), WithTrashRetention(testRetention), WithClock(c))
+	ids := make(map[string]string, len(names))
+	for _, name := range names {
+		a, err := p.Add(context.Background(), models.Audio{Name: name, Duration: time.Minute})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = a.Id
+	}
+	return p, ids
+}
+
+func names(t *testing.T, p *MemPlaylist) []string {
+	t.Helper()
+	audios, _, err := p.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range audios {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func TestMemPlaylist_Restore(t *testing.T) {
+	type step struct {
+		// op is "delete", "restore" or "move"
+		op   string
+		name string
+		// index is the index to move to or the expected index of the restored audio
+		index int
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  []string
+	}{
+		{
+			name:  "middle",
+			steps: []step{{"delete", "b", 0}, {"restore", "b", 1}},
+			want:  []string{"a", "b", "c", "d"},
+		},
+		{
+			name:  "first",
+			steps: []step{{"delete", "a", 0}, {"restore", "a", 0}},
+			want:  []string{"a", "b", "c", "d"},
+		},
+		{
+			name:  "last",
+			steps: []step{{"delete", "d", 0}, {"restore", "d", 3}},
+			want:  []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "reverse order of deletion",
+			steps: []step{
+				{"delete", "b", 0}, {"delete", "c", 0}, {"delete", "a", 0},
+				{"restore", "a", 0}, {"restore", "c", 1}, {"restore", "b", 1},
+			},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:  "neighbours not adjacent",
+			steps: []step{{"delete", "b", 0}, {"move", "d", 1}, {"restore", "b", 1}},
+			want:  []string{"a", "b", "d", "c"},
+		},
+		{
+			name:  "preceding deleted",
+			steps: []step{{"delete", "b", 0}, {"delete", "a", 0}, {"restore", "b", 0}},
+			want:  []string{"b", "c", "d"},
+		},
+		{
+			name:  "preceding moved",
+			steps: []step{{"delete", "c", 0}, {"move", "b", 3}, {"restore", "c", 3}},
+			want:  []string{"a", "d", "b", "c"},
+		},
+		{
+			name:  "both neighbours deleted",
+			steps: []step{{"delete", "c", 0}, {"delete", "b", 0}, {"delete", "d", 0}, {"restore", "c", 1}},
+			want:  []string{"a", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, ids := newTestPlaylist(t, &fakeClock{now: time.Now()}, "a", "b", "c", "d")
+			for _, s := range tt.steps {
+				switch s.op {
+				case "delete":
+					if err := p.Delete(ctx, ids[s.name], 0); err != nil {
+						t.Fatalf("Delete(%s) error = %v", s.name, err)
+					}
+				case "move":
+					if err := p.Move(ctx, ids[s.name], s.index); err != nil {
+						t.Fatalf("Move(%s) error = %v", s.name, err)
+					}
+				case "restore":
+					a, index, err := p.Restore(ctx, ids[s.name])
+					if err != nil {
+						t.Fatalf("Restore(%s) error = %v", s.name, err)
+					}
+					if a.Id != ids[s.name] || a.Name != s.name {
+						t.Errorf("Restore(%s) audio = %+v", s.name, a)
+					}
+					if index != s.index {
+						t.Errorf("Restore(%s) index = %d, want %d", s.name, index, s.index)
+					}
+				}
+			}
+			if got := names(t, p); !slices.Equal(got, tt.want) {
+				t.Errorf("playlist = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemPlaylist_RestoreErrors(t *testing.T) {
+	ctx := context.Background()
+	p, ids := newTestPlaylist(t, &fakeClock{now: time.Now()}, "a", "b")
+	if err := p.Delete(ctx, ids["a"], 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Restore(ctx, ids["a"]); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "not deleted", id: ids["b"]},
+		{name: "already restored", id: ids["a"]},
+		{name: "unknown", id: "cn0000000000000000a0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := p.Restore(ctx, tt.id); !errors.Is(err, playlist.ErrNotInTrash) {
+				t.Errorf("Restore() error = %v, want %v", err, playlist.ErrNotInTrash)
+			}
+		})
+	}
+}
+
+func TestMemPlaylist_TrashRetention(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &fakeClock{now: start}
+	p, ids := newTestPlaylist(t, c, "a", "b", "c")
+
+	if err := p.Delete(ctx, ids["a"], 0); err != nil {
+		t.Fatal(err)
+	}
+	c.advance(testRetention / 2)
+	if err := p.Delete(ctx, ids["b"], 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		// want are the names in the trash, the latest deleted first
+		want []string
+	}{
+		{name: "both kept", want: []string{"b", "a"}},
+		{name: "just before the first expires", advance: testRetention/2 - time.Nanosecond, want: []string{"b", "a"}},
+		{name: "first expired", advance: time.Nanosecond, want: []string{"b"}},
+		{name: "all expired", advance: testRetention / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.advance(tt.advance)
+			trash, err := p.ListTrash(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, a := range trash {
+				got = append(got, a.Name)
+				if want := a.DeletedAt.Add(testRetention); !a.ExpiresAt.Equal(want) {
+					t.Errorf("%s expires at %s, want %s", a.Name, a.ExpiresAt, want)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("trash = %v, want %v", got, tt.want)
+			}
+			for _, name := range []string{"a", "b"} {
+				if slices.Contains(tt.want, name) {
+					continue
+				}
+				if _, _, err := p.Restore(ctx, ids[name]); !errors.Is(err, playlist.ErrNotInTrash) {
+					t.Errorf("Restore(%s) of expired error = %v, want %v", name, err, playlist.ErrNotInTrash)
+				}
+			}
+		})
+	}
+}
+
+func TestMemPlaylist_NoTrash(t *testing.T) {
+	ctx := context.Background()
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	a, err := p.Add(ctx, models.Audio{Name: "a", Duration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Delete(ctx, a.Id, 0); err != nil {
+		t.Fatal(err)
+	}
+	if trash, _ := p.ListTrash(ctx); len(trash) != 0 {
+		t.Errorf("trash = %v, want empty", trash)
+	}
+	if _, _, err := p.Restore(ctx, a.Id); !errors.Is(err, playlist.ErrNotInTrash) {
+		t.Errorf("Restore() error = %v, want %v", err, playlist.ErrNotInTrash)
+	}
+}
+
+func TestMemPlaylist_PurgeTrash(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want int
+		left []string
+	}{
+		{name: "all", want: 3},
+		{name: "some", ids: []string{"a", "c"}, want: 2, left: []string{"b"}},
+		{name: "not in trash", ids: []string{"d"}, want: 0, left: []string{"c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			p, ids := newTestPlaylist(t, &fakeClock{now: time.Now()}, "a", "b", "c", "d")
+			for _, name := range []string{"a", "b", "c"} {
+				if err := p.Delete(ctx, ids[name], 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var purge []string
+			for _, name := range tt.ids {
+				purge = append(purge, ids[name])
+			}
+			n, err := p.PurgeTrash(ctx, purge...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Errorf("PurgeTrash() = %d, want %d", n, tt.want)
+			}
+			trash, _ := p.ListTrash(ctx)
+			var left []string
+			for _, a := range trash {
+				left = append(left, a.Name)
+			}
+			if !slices.Equal(left, tt.left) {
+				t.Errorf("trash = %v, want %v", left, tt.left)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package memory
+
+import "github.com/Karzoug/gocloudcamp/internal/models"
+
+// node is an audio in the playlist order.
+type node struct {
+	audio models.Audio
+
+	left, right, parent *node
+	priority            uint64
+	// size is the number of nodes in the subtree of the node.
+	size int
+}
+
+func size(n *node) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// update restores the size of n and the parent of its children after they change.
+func (n *node) update() {
+	n.size = 1 + size(n.left) + size(n.right)
+	if n.left != nil {
+		n.left.parent = n
+	}
+	if n.right != nil {
+		n.right.parent = n
+	}
+}
+
+// tree orders nodes by position. It is an implicit treap: a binary search tree
+// by position with heap ordered random priorities keeping it balanced, so that
+// insertion, removal and finding the position of a node take O(log n) time.
+type tree struct {
+	root *node
+	seed uint64
+}
+
+func (t *tree) len() int {
+	return size(t.root)
+}
+
+// newNode returns a detached node with a random priority.
+func (t *tree) newNode(a models.Audio) *node {
+	// xorshift64*
+	if t.seed == 0 {
+		t.seed = 0x9e3779b97f4a7c15
+	}
+	t.seed ^= t.seed >> 12
+	t.seed ^= t.seed << 25
+	t.seed ^= t.seed >> 27
+	return &node{audio: a, priority: t.seed * 2685821657736338717, size: 1}
+}
+
+// insert puts the detached node n at the position, a position past the end puts it at the end.
+func (t *tree) insert(n *node, pos int) {
+	l, r := split(t.root, pos)
+	t.setRoot(merge(merge(l, n), r))
+}
+
+// pushBack puts the detached node n at the end.
+func (t *tree) pushBack(n *node) {
+	t.setRoot(merge(t.root, n))
+}
+
+// remove detaches n from the tree.
+func (t *tree) remove(n *node) {
+	l, r := split(t.root, t.index(n))
+	_, r = split(r, 1)
+	t.setRoot(merge(l, r))
+	n.left, n.right, n.parent, n.size = nil, nil, nil, 1
+}
+
+func (t *tree) setRoot(n *node) {
+	t.root = n
+	if n != nil {
+		n.parent = nil
+	}
+}
+
+// index returns the position of n.
+func (t *tree) index(n *node) int {
+	pos := size(n.left)
+	for ; n.parent != nil; n = n.parent {
+		if n == n.parent.right {
+			pos += size(n.parent.left) + 1
+		}
+	}
+	return pos
+}
+
+func (t *tree) front() *node {
+	return leftmost(t.root)
+}
+
+func (t *tree) back() *node {
+	return rightmost(t.root)
+}
+
+// next returns the node after n, nil if n is the last one.
+func next(n *node) *node {
+	if n.right != nil {
+		return leftmost(n.right)
+	}
+	for n.parent != nil && n == n.parent.right {
+		n = n.parent
+	}
+	return n.parent
+}
+
+// prev returns the node before n, nil if n is the first one.
+func prev(n *node) *node {
+	if n.left != nil {
+		return rightmost(n.left)
+	}
+	for n.parent != nil && n == n.parent.left {
+		n = n.parent
+	}
+	return n.parent
+}
+
+func leftmost(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func rightmost(n *node) *node {
+	if n == nil {
+		return nil
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// split splits the subtree of n into the first k nodes and the rest.
+// Parents of the returned roots are not reset.
+func split(n *node, k int) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+	if size(n.left) < k {
+		l, r := split(n.right, k-size(n.left)-1)
+		n.right = l
+		n.update()
+		return n, r
+	}
+	l, r := split(n.left, k)
+	n.left = r
+	n.update()
+	return l, n
+}
+
+// merge joins the subtrees keeping the nodes of l before the nodes of r.
+func merge(l, r *node) *node {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.priority > r.priority {
+		l.right = merge(l.right, r)
+		l.update()
+		return l
+	}
+	r.left = merge(l, r.left)
+	r.update()
+	return r
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+)
+
+// treeOp changes a tree and the slice of names modelling its order.
+type treeOp func(t *tree, nodes map[string]*node, want []string) []string
+
+func insertOp(name string, pos int) treeOp {
+	return func(t *tree, nodes map[string]*node, want []string) []string {
+		n := t.newNode(models.Audio{Name: name})
+		nodes[name] = n
+		t.insert(n, pos)
+		return slices.Insert(want, min(pos, len(want)), name)
+	}
+}
+
+func pushBackOp(name string) treeOp {
+	return func(t *tree, nodes map[string]*node, want []string) []string {
+		n := t.newNode(models.Audio{Name: name})
+		nodes[name] = n
+		t.pushBack(n)
+		return append(want, name)
+	}
+}
+
+func removeOp(name string) treeOp {
+	return func(t *tree, nodes map[string]*node, want []string) []string {
+		t.remove(nodes[name])
+		delete(nodes, name)
+		return slices.Delete(want, slices.Index(want, name), slices.Index(want, name)+1)
+	}
+}
+
+// moveOp moves the node as MemPlaylist.Move does.
+func moveOp(name string, pos int) treeOp {
+	return func(t *tree, nodes map[string]*node, want []string) []string {
+		t.remove(nodes[name])
+		t.insert(nodes[name], pos)
+		i := slices.Index(want, name)
+		want = slices.Delete(want, i, i+1)
+		return slices.Insert(want, min(pos, len(want)), name)
+	}
+}
+
+func TestTree(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []treeOp
+		want []string
+	}{
+		{name: "empty"},
+		{
+			name: "push back",
+			ops:  []treeOp{pushBackOp("a"), pushBackOp("b"), pushBackOp("c")},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "insert at front",
+			ops:  []treeOp{insertOp("a", 0), insertOp("b", 0), insertOp("c", 0)},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "insert in the middle",
+			ops:  []treeOp{pushBackOp("a"), pushBackOp("c"), insertOp("b", 1)},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "insert past the end",
+			ops:  []treeOp{pushBackOp("a"), insertOp("b", 10)},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "remove first, middle and last",
+			ops: []treeOp{
+				pushBackOp("a"), pushBackOp("b"), pushBackOp("c"), pushBackOp("d"), pushBackOp("e"),
+				removeOp("a"), removeOp("c"), removeOp("e"),
+			},
+			want: []string{"b", "d"},
+		},
+		{
+			name: "remove all",
+			ops:  []treeOp{pushBackOp("a"), pushBackOp("b"), removeOp("b"), removeOp("a")},
+		},
+		{
+			name: "reinsert removed",
+			ops:  []treeOp{pushBackOp("a"), pushBackOp("b"), pushBackOp("c"), moveOp("a", 2)},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "move to front",
+			ops:  []treeOp{pushBackOp("a"), pushBackOp("b"), pushBackOp("c"), moveOp("c", 0)},
+			want: []string{"c", "a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &tree{}
+			nodes := make(map[string]*node)
+			var want []string
+			for _, op := range tt.ops {
+				want = op(tr, nodes, want)
+			}
+			if !slices.Equal(want, tt.want) {
+				t.Fatalf("model order = %v, want %v", want, tt.want)
+			}
+			checkTree(t, tr, nodes, tt.want)
+		})
+	}
+}
+
+// TestTree_Random compares the tree with a slice after every random operation.
+func TestTree_Random(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tr := &tree{}
+	nodes := make(map[string]*node)
+	var want []string
+	for i := 0; i < 2000; i++ {
+		var op treeOp
+		switch k := rnd.Intn(10); {
+		case len(want) == 0 || k < 4:
+			op = insertOp(strconv.Itoa(i), rnd.Intn(len(want)+2))
+		case k < 5:
+			op = pushBackOp(strconv.Itoa(i))
+		case k < 8:
+			op = removeOp(want[rnd.Intn(len(want))])
+		default:
+			op = moveOp(want[rnd.Intn(len(want))], rnd.Intn(len(want)+1))
+		}
+		want = op(tr, nodes, want)
+		checkTree(t, tr, nodes, want)
+		if t.Failed() {
+			t.Fatalf("after operation %d", i)
+		}
+	}
+}
+
+// checkTree checks the order of the tree in both directions, the positions
+// of its nodes and the invariants of the treap.
+func checkTree(t *testing.T, tr *tree, nodes map[string]*node, want []string) {
+	t.Helper()
+
+	if got := tr.len(); got != len(want) {
+		t.Errorf("len() = %d, want %d", got, len(want))
+	}
+
+	var forward []string
+	for n := tr.front(); n != nil; n = next(n) {
+		forward = append(forward, n.audio.Name)
+	}
+	if !slices.Equal(forward, want) {
+		t.Errorf("forward order = %v, want %v", forward, want)
+	}
+	var backward []string
+	for n := tr.back(); n != nil; n = prev(n) {
+		backward = append(backward, n.audio.Name)
+	}
+	slices.Reverse(backward)
+	if !slices.Equal(backward, want) {
+		t.Errorf("backward order = %v, want %v", backward, want)
+	}
+
+	for i, name := range want {
+		if got := tr.index(nodes[name]); got != i {
+			t.Errorf("index(%s) = %d, want %d", name, got, i)
+		}
+	}
+
+	if tr.root != nil && tr.root.parent != nil {
+		t.Error("root has a parent")
+	}
+	checkNode(t, tr.root)
+}
+
+// checkNode checks sizes, parent links and heap order of priorities in the subtree of n.
+func checkNode(t *testing.T, n *node) {
+	t.Helper()
+
+	if n == nil {
+		return
+	}
+	if want := 1 + size(n.left) + size(n.right); n.size != want {
+		t.Errorf("size of %s = %d, want %d", n.audio.Name, n.size, want)
+	}
+	for _, c := range []*node{n.left, n.right} {
+		if c == nil {
+			continue
+		}
+		if c.parent != n {
+			t.Errorf("parent of %s is not %s", c.audio.Name, n.audio.Name)
+		}
+		if c.priority > n.priority {
+			t.Errorf("priority of %s is above the priority of its parent %s", c.audio.Name, n.audio.Name)
+		}
+		checkNode(t, c)
+	}
+}
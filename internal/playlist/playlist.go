@@ -6,21 +6,57 @@ import (
 	"github.com/Karzoug/gocloudcamp/internal/models"
 )
 
+// Playlist is an ordered AudioRepository with the current audio.
+// The current audio is played by the player, so it must be updated and deleted
+// only by the player, which adjusts the playback to the change.
 type Playlist interface {
 	AudioRepository
-	Current() *models.Audio
-	CurrentToFront() *models.Audio
-	CurrentToNext() *models.Audio
-	CurrentToPrev() *models.Audio
-	Front() *models.Audio
-	Back() *models.Audio
+	Current(ctx context.Context) *models.Audio
+	CurrentToFront(ctx context.Context) *models.Audio
+	CurrentToNext(ctx context.Context) *models.Audio
+	CurrentToPrev(ctx context.Context) *models.Audio
+	Front(ctx context.Context) *models.Audio
+	Back(ctx context.Context) *models.Audio
 }
 
+// AudioRepository stores audios. Every audio has a version that is increased
+// on each change, the repository as a whole has a revision that is increased
+// on each change of any audio.
 type AudioRepository interface {
 	Add(ctx context.Context, a models.Audio) (*models.Audio, error)
 	Get(ctx context.Context, id string) (*models.Audio, error)
+	// Index returns the position of the audio in the playlist.
+	Index(ctx context.Context, id string) (int, error)
+	// Update replaces the audio with the same id, the current audio included.
+	// If a.Version is not zero, it must be equal to the stored version,
+	// otherwise ErrVersionMismatch is returned.
 	Update(ctx context.Context, a models.Audio) (*models.Audio, error)
-	Delete(ctx context.Context, id string) error
-	List(ctx context.Context) ([]models.Audio, error)
+	// Delete removes the audio and keeps it in the trash. If version is not zero,
+	// it must be equal to the stored version, otherwise ErrVersionMismatch is returned.
+	// Deleting the current audio makes the following one current,
+	// there is no current audio if it was the last.
+	Delete(ctx context.Context, id string, version uint64) error
+	// Move moves the audio to the index in the playlist,
+	// an index past the end moves it to the end.
+	Move(ctx context.Context, id string, index int) error
+	// Len returns the number of audios.
+	Len(ctx context.Context) int
+	// List returns all audios and the revision they correspond to.
+	List(ctx context.Context) ([]models.Audio, uint64, error)
+	// ListTrash returns deleted audios kept in the trash, the latest deleted first.
+	ListTrash(ctx context.Context) ([]models.DeletedAudio, error)
+	// Restore moves the audio from the trash back to the playlist, to its original
+	// position where possible, and returns it with the index it is restored to.
+	// ErrNotInTrash is returned if the trash does not keep the audio.
+	Restore(ctx context.Context, id string) (*models.Audio, int, error)
+	// PurgeTrash removes audios with the ids from the trash permanently,
+	// all of them if no id is given, and returns the number of removed audios.
+	PurgeTrash(ctx context.Context, ids ...string) (int, error)
 	Close() error
 }
+
+// Checker is implemented by playlists whose storage backend may become unavailable.
+type Checker interface {
+	// Check returns an error if the storage backend is not writable.
+	Check(ctx context.Context) error
+}
@@ -0,0 +1,164 @@
+package playlist
+
+import (
+	"context"
+
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Karzoug/gocloudcamp/internal/playlist")
+
+type tracedPlaylist struct {
+	pl Playlist
+}
+
+// WithTracing returns a playlist that records a span for each call
+// made within a traced operation.
+func WithTracing(pl Playlist) Playlist {
+	return tracedPlaylist{pl: pl}
+}
+
+// start starts a span only if ctx already has one, so that calls
+// outside of traced operations (e.g. metrics scraping) do not create traces.
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, "playlist."+name, trace.WithAttributes(attrs...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t tracedPlaylist) Current(ctx context.Context) *models.Audio {
+	ctx, span := start(ctx, "Current")
+	defer span.End()
+	return t.pl.Current(ctx)
+}
+
+func (t tracedPlaylist) CurrentToFront(ctx context.Context) *models.Audio {
+	ctx, span := start(ctx, "CurrentToFront")
+	defer span.End()
+	return t.pl.CurrentToFront(ctx)
+}
+
+func (t tracedPlaylist) CurrentToNext(ctx context.Context) *models.Audio {
+	ctx, span := start(ctx, "CurrentToNext")
+	defer span.End()
+	return t.pl.CurrentToNext(ctx)
+}
+
+func (t tracedPlaylist) CurrentToPrev(ctx context.Context) *models.Audio {
+	ctx, span := start(ctx, "CurrentToPrev")
+	defer span.End()
+	return t.pl.CurrentToPrev(ctx)
+}
+
+func (t tracedPlaylist) Front(ctx context.Context) *models.Audio {
+	ctx, span := start(ctx, "Front")
+	defer span.End()
+	return t.pl.Front(ctx)
+}
+
+func (t tracedPlaylist) Back(ctx context.Context) *models.Audio {
+	ctx, span := start(ctx, "Back")
+	defer span.End()
+	return t.pl.Back(ctx)
+}
+
+func (t tracedPlaylist) Add(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	ctx, span := start(ctx, "Add")
+	res, err := t.pl.Add(ctx, a)
+	end(span, err)
+	return res, err
+}
+
+func (t tracedPlaylist) Get(ctx context.Context, id string) (*models.Audio, error) {
+	ctx, span := start(ctx, "Get", attribute.String("audio.id", id))
+	res, err := t.pl.Get(ctx, id)
+	end(span, err)
+	return res, err
+}
+
+func (t tracedPlaylist) Index(ctx context.Context, id string) (int, error) {
+	ctx, span := start(ctx, "Index", attribute.String("audio.id", id))
+	res, err := t.pl.Index(ctx, id)
+	end(span, err)
+	return res, err
+}
+
+func (t tracedPlaylist) Len(ctx context.Context) int {
+	ctx, span := start(ctx, "Len")
+	defer span.End()
+	return t.pl.Len(ctx)
+}
+
+func (t tracedPlaylist) Update(ctx context.Context, a models.Audio) (*models.Audio, error) {
+	ctx, span := start(ctx, "Update", attribute.String("audio.id", a.Id))
+	res, err := t.pl.Update(ctx, a)
+	end(span, err)
+	return res, err
+}
+
+func (t tracedPlaylist) Delete(ctx context.Context, id string, version uint64) error {
+	ctx, span := start(ctx, "Delete", attribute.String("audio.id", id))
+	err := t.pl.Delete(ctx, id, version)
+	end(span, err)
+	return err
+}
+
+func (t tracedPlaylist) Move(ctx context.Context, id string, index int) error {
+	ctx, span := start(ctx, "Move", attribute.String("audio.id", id), attribute.Int("index", index))
+	err := t.pl.Move(ctx, id, index)
+	end(span, err)
+	return err
+}
+
+func (t tracedPlaylist) List(ctx context.Context) ([]models.Audio, uint64, error) {
+	ctx, span := start(ctx, "List")
+	res, rev, err := t.pl.List(ctx)
+	end(span, err)
+	return res, rev, err
+}
+
+func (t tracedPlaylist) ListTrash(ctx context.Context) ([]models.DeletedAudio, error) {
+	ctx, span := start(ctx, "ListTrash")
+	res, err := t.pl.ListTrash(ctx)
+	end(span, err)
+	return res, err
+}
+
+func (t tracedPlaylist) Restore(ctx context.Context, id string) (*models.Audio, int, error) {
+	ctx, span := start(ctx, "Restore", attribute.String("audio.id", id))
+	res, index, err := t.pl.Restore(ctx, id)
+	end(span, err)
+	return res, index, err
+}
+
+func (t tracedPlaylist) PurgeTrash(ctx context.Context, ids ...string) (int, error) {
+	ctx, span := start(ctx, "PurgeTrash", attribute.StringSlice("audio.ids", ids))
+	n, err := t.pl.PurgeTrash(ctx, ids...)
+	end(span, err)
+	return n, err
+}
+
+func (t tracedPlaylist) Close() error {
+	return t.pl.Close()
+}
+
+// Check delegates to the wrapped playlist if it is a Checker.
+func (t tracedPlaylist) Check(ctx context.Context) error {
+	if c, ok := t.pl.(Checker); ok {
+		return c.Check(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// idleTimeout is the time after which limiters of inactive clients are removed.
+	idleTimeout     = 10 * time.Minute
+	cleanupInterval = time.Minute
+)
+
+// Limit is a token bucket: rate tokens per second with burst size.
+// Zero rate means no limit.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Limits are loaded from a JSON file:
+//
+//	{
+//	  "default": {"rate": 10, "burst": 20},
+//	  "methods": {"/grpcapi.PlayerService/Next": {"rate": 2, "burst": 5}}
+//	}
+type Limits struct {
+	Default Limit            `json:"default"`
+	Methods map[string]Limit `json:"methods"`
+}
+
+func (l Limits) forMethod(fullMethod string) Limit {
+	if ml, ok := l.Methods[fullMethod]; ok {
+		return ml
+	}
+	return l.Default
+}
+
+// LoadLimits reads limits from the JSON file.
+func LoadLimits(filename string) (Limits, error) {
+	var l Limits
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return l, fmt.Errorf("read rate limits file error: %w", err)
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, fmt.Errorf("parse rate limits file error: %w", err)
+	}
+	return l, nil
+}
+
+type limiterKey struct {
+	client string
+	method string
+}
+
+type limiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter limits calls of every client per method, clients are identified
+// by the authenticated principal or, if there is none, by the peer address.
+type Limiter struct {
+	limits Limits
+
+	mtx      sync.Mutex
+	limiters map[limiterKey]*limiter
+}
+
+func New(limits Limits) *Limiter {
+	return &Limiter{
+		limits:   limits,
+		limiters: make(map[limiterKey]*limiter),
+	}
+}
+
+// Cleanup removes limiters of inactive clients periodically, until ctx is done.
+func (l *Limiter) Cleanup(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.removeIdle(now)
+		}
+	}
+}
+
+// removeIdle removes limiters not used within idleTimeout before now.
+func (l *Limiter) removeIdle(now time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for k, lim := range l.limiters {
+		if now.Sub(lim.lastSeen) > idleTimeout {
+			delete(l.limiters, k)
+		}
+	}
+}
+
+// allow returns zero if the call is allowed, otherwise the time to wait before retrying.
+func (l *Limiter) allow(ctx context.Context, fullMethod string) time.Duration {
+	ml := l.limits.forMethod(fullMethod)
+	if ml.Rate <= 0 {
+		return 0
+	}
+
+	key := limiterKey{client: clientKey(ctx), method: fullMethod}
+	now := time.Now()
+
+	l.mtx.Lock()
+	lim, ok := l.limiters[key]
+	if !ok {
+		burst := ml.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		lim = &limiter{Limiter: rate.NewLimiter(rate.Limit(ml.Rate), burst)}
+		l.limiters[key] = lim
+	}
+	lim.lastSeen = now
+	l.mtx.Unlock()
+
+	r := lim.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+func clientKey(ctx context.Context) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return "principal:" + p.Subject
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "peer:" + host
+		}
+		return "peer:" + p.Addr.String()
+	}
+	return ""
+}
+
+func exhausted(fullMethod string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded for %s, retry after %s", fullMethod, retryAfter))
+	if dst, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = dst
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor rejects calls exceeding the limits with ResourceExhausted
+// and RetryInfo details. It must be chained after the authentication interceptor.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if d := l.allow(ctx, info.FullMethod); d > 0 {
+			return nil, exhausted(info.FullMethod, d)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the stream counterpart of UnaryServerInterceptor.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if d := l.allow(ss.Context(), info.FullMethod); d > 0 {
+			return exhausted(info.FullMethod, d)
+		}
+		return handler(srv, ss)
+	}
+}
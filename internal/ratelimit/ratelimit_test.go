@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	nextMethod = "/grpcapi.PlayerService/Next"
+	playMethod = "/grpcapi.PlayerService/Play"
+)
+
+// call is a call made by the client, principal or peer address, of the method.
+type call struct {
+	principal string
+	peer      string
+	method    string
+}
+
+func (c call) context() context.Context {
+	ctx := context.Background()
+	if c.peer != "" {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(c.peer), Port: 50000}})
+	}
+	if c.principal != "" {
+		ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: c.principal})
+	}
+	return ctx
+}
+
+func TestLimiter(t *testing.T) {
+	// the rate is low enough for tokens not to be refilled during the test
+	limits := Limits{
+		Default: Limit{Rate: 0.001, Burst: 2},
+		Methods: map[string]Limit{
+			nextMethod: {Rate: 0.001, Burst: 1},
+		},
+	}
+	alice := call{principal: "alice", peer: "10.0.0.1", method: playMethod}
+	tests := []struct {
+		name   string
+		limits Limits
+		calls  []call
+		// allowed tells which calls pass
+		allowed []bool
+	}{
+		{
+			name:    "default burst",
+			limits:  limits,
+			calls:   []call{alice, alice, alice},
+			allowed: []bool{true, true, false},
+		},
+		{
+			name:    "method override",
+			limits:  limits,
+			calls:   []call{{principal: "alice", method: nextMethod}, {principal: "alice", method: nextMethod}},
+			allowed: []bool{true, false},
+		},
+		{
+			name:    "methods are limited separately",
+			limits:  limits,
+			calls:   []call{{principal: "alice", method: nextMethod}, alice, alice},
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:    "principals are limited separately",
+			limits:  limits,
+			calls:   []call{alice, alice, {principal: "bob", peer: "10.0.0.1", method: playMethod}},
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:    "principal is keyed regardless of peer",
+			limits:  limits,
+			calls:   []call{alice, {principal: "alice", peer: "10.0.0.2", method: playMethod}, alice},
+			allowed: []bool{true, true, false},
+		},
+		{
+			name:    "anonymous keyed by peer address",
+			limits:  limits,
+			calls:   []call{{peer: "10.0.0.3", method: playMethod}, {peer: "10.0.0.3", method: playMethod}, {peer: "10.0.0.3", method: playMethod}},
+			allowed: []bool{true, true, false},
+		},
+		{
+			name:    "peers are limited separately",
+			limits:  limits,
+			calls:   []call{{peer: "10.0.0.3", method: playMethod}, {peer: "10.0.0.3", method: playMethod}, {peer: "10.0.0.4", method: playMethod}},
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:    "principal and its peer are limited separately",
+			limits:  limits,
+			calls:   []call{alice, alice, {peer: "10.0.0.1", method: playMethod}},
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:    "zero rate is unlimited",
+			limits:  Limits{Methods: map[string]Limit{nextMethod: {Rate: 0.001, Burst: 1}}},
+			calls:   []call{alice, alice, alice},
+			allowed: []bool{true, true, true},
+		},
+		{
+			name:    "zero burst allows one call",
+			limits:  Limits{Default: Limit{Rate: 0.001}},
+			calls:   []call{alice, alice},
+			allowed: []bool{true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := New(tt.limits).UnaryServerInterceptor()
+			for i, c := range tt.calls {
+				handled := false
+				_, err := interceptor(c.context(), nil, &grpc.UnaryServerInfo{FullMethod: c.method}, func(context.Context, any) (any, error) {
+					handled = true
+					return nil, nil
+				})
+				if handled != tt.allowed[i] || (err == nil) != tt.allowed[i] {
+					t.Errorf("call %d: handled = %v, error = %v, want allowed %v", i, handled, err, tt.allowed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_RetryInfo(t *testing.T) {
+	l := New(Limits{Default: Limit{Rate: 1, Burst: 1}})
+	interceptor := l.StreamServerInterceptor()
+	ss := &contextStream{ctx: call{principal: "alice"}.context()}
+	info := &grpc.StreamServerInfo{FullMethod: "/grpcapi.PlayerService/WatchEvents"}
+	handler := func(any, grpc.ServerStream) error { return nil }
+
+	if err := interceptor(nil, ss, info, handler); err != nil {
+		t.Fatal(err)
+	}
+	err := interceptor(nil, ss, info, handler)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("error = %v, want ResourceExhausted", err)
+	}
+	var delay time.Duration
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			delay = ri.GetRetryDelay().AsDuration()
+		}
+	}
+	// a token is refilled in a second
+	if delay <= 0 || delay > time.Second {
+		t.Errorf("retry delay = %s, want in (0, 1s]", delay)
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestLimiter_RemoveIdle(t *testing.T) {
+	l := New(Limits{Default: Limit{Rate: 0.001, Burst: 1}})
+	alice := call{principal: "alice", method: playMethod}
+	bob := call{principal: "bob", method: playMethod}
+	for _, c := range []call{alice, bob} {
+		if d := l.allow(c.context(), c.method); d != 0 {
+			t.Fatalf("%s is limited", c.principal)
+		}
+	}
+	// alice is active a minute later than bob
+	seen := time.Now()
+	l.limiters[limiterKey{client: "principal:bob", method: playMethod}].lastSeen = seen
+	l.limiters[limiterKey{client: "principal:alice", method: playMethod}].lastSeen = seen.Add(time.Minute)
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		// kept tells whose limiters are kept
+		kept map[string]bool
+	}{
+		{name: "not idle", after: idleTimeout, kept: map[string]bool{"alice": true, "bob": true}},
+		{name: "bob idle", after: idleTimeout + time.Second, kept: map[string]bool{"alice": true}},
+		{name: "all idle", after: idleTimeout + 2*time.Minute, kept: map[string]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l.removeIdle(seen.Add(tt.after))
+			for _, c := range []call{alice, bob} {
+				_, ok := l.limiters[limiterKey{client: "principal:" + c.principal, method: c.method}]
+				if ok != tt.kept[c.principal] {
+					t.Errorf("limiter of %s kept = %v, want %v", c.principal, ok, tt.kept[c.principal])
+				}
+			}
+		})
+	}
+
+	// a removed bucket starts full
+	if d := l.allow(bob.context(), bob.method); d != 0 {
+		t.Errorf("bob is limited after removal of the idle bucket, retry after %s", d)
+	}
+}
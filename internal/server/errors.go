@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/audit"
+	"github.com/Karzoug/gocloudcamp/internal/events"
+	"github.com/Karzoug/gocloudcamp/internal/player"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const audioResourceType = "audio"
+
+// queueFullRetryDelay is the time clients are asked to wait
+// before retrying a command rejected by the full player queue.
+const queueFullRetryDelay = 100 * time.Millisecond
+
+type errorMapping struct {
+	target error
+	code   codes.Code
+	reason grpcapi.ErrorReason
+}
+
+var errorMappings = []errorMapping{
+	{playlist.ErrNotFound, codes.NotFound, grpcapi.ErrorReason_AUDIO_NOT_FOUND},
+	{playlist.ErrCurrentAudio, codes.FailedPrecondition, grpcapi.ErrorReason_AUDIO_IS_CURRENT},
+	{playlist.ErrVersionMismatch, codes.Aborted, grpcapi.ErrorReason_AUDIO_VERSION_MISMATCH},
+	{playlist.ErrNotInTrash, codes.NotFound, grpcapi.ErrorReason_AUDIO_NOT_IN_TRASH},
+	{playlist.ErrNothingToUndo, codes.FailedPrecondition, grpcapi.ErrorReason_NOTHING_TO_UNDO},
+	{playlist.ErrNothingToRedo, codes.FailedPrecondition, grpcapi.ErrorReason_NOTHING_TO_REDO},
+	{playlist.ErrHistoryDisabled, codes.FailedPrecondition, grpcapi.ErrorReason_HISTORY_DISABLED},
+	{player.ErrNoAudio, codes.NotFound, grpcapi.ErrorReason_NO_AUDIO},
+	{player.ErrPlayerClosed, codes.Unavailable, grpcapi.ErrorReason_PLAYER_CLOSED},
+	{player.ErrPositionOutOfRange, codes.OutOfRange, grpcapi.ErrorReason_POSITION_OUT_OF_RANGE},
+	{player.ErrSleepTimerInPast, codes.InvalidArgument, grpcapi.ErrorReason_SLEEP_TIMER_IN_PAST},
+	{player.ErrQueueFull, codes.ResourceExhausted, grpcapi.ErrorReason_COMMAND_QUEUE_FULL},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, grpcapi.ErrorReason_DEADLINE_EXCEEDED},
+	{context.Canceled, codes.Canceled, grpcapi.ErrorReason_CANCELED},
+	{audit.ErrDisabled, codes.FailedPrecondition, grpcapi.ErrorReason_AUDIT_DISABLED},
+	{events.ErrSlowSubscriber, codes.ResourceExhausted, grpcapi.ErrorReason_SLOW_SUBSCRIBER},
+	{events.ErrClosed, codes.Unavailable, grpcapi.ErrorReason_SHUTTING_DOWN},
+}
+
+// audioError binds an error to the audio it relates to.
+type audioError struct {
+	id  string
+	err error
+}
+
+func (e audioError) Error() string {
+	return e.err.Error()
+}
+
+func (e audioError) Unwrap() error {
+	return e.err
+}
+
+func withAudio(id string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return audioError{id: id, err: err}
+}
+
+// UnaryErrorInterceptor translates errors returned by handlers to status errors.
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, toStatusError(err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamErrorInterceptor is the stream counterpart of UnaryErrorInterceptor.
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatusError(err)
+		}
+		return nil
+	}
+}
+
+// toStatusError maps domain errors to status errors with ErrorInfo
+// and, if the error relates to an audio, ResourceInfo details.
+func toStatusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, m := range errorMappings {
+		if !errors.Is(err, m.target) {
+			continue
+		}
+		st := status.New(m.code, err.Error())
+		details := []protoadapt.MessageV1{
+			&errdetails.ErrorInfo{
+				Reason: m.reason.String(),
+				Domain: grpcapi.ErrorDomain,
+			},
+		}
+		if errors.Is(err, player.ErrQueueFull) {
+			details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(queueFullRetryDelay)})
+		}
+		var ae audioError
+		if errors.As(err, &ae) {
+			details = append(details, &errdetails.ResourceInfo{
+				ResourceType: audioResourceType,
+				ResourceName: ae.id,
+				Description:  err.Error(),
+			})
+		}
+		if dst, derr := st.WithDetails(details...); derr == nil {
+			st = dst
+		}
+		return st.Err()
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/audit"
+	"github.com/Karzoug/gocloudcamp/internal/events"
+	"github.com/Karzoug/gocloudcamp/internal/player"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		code       codes.Code
+		reason     grpcapi.ErrorReason
+		audioId    string
+		retryDelay time.Duration
+	}{
+		{name: "not found", err: playlist.ErrNotFound, code: codes.NotFound, reason: grpcapi.ErrorReason_AUDIO_NOT_FOUND},
+		{
+			name:    "not found audio",
+			err:     withAudio(testAudioId, playlist.ErrNotFound),
+			code:    codes.NotFound,
+			reason:  grpcapi.ErrorReason_AUDIO_NOT_FOUND,
+			audioId: testAudioId,
+		},
+		{
+			name:    "wrapped audio error",
+			err:     fmt.Errorf("delete audio error: %w", withAudio(testAudioId, playlist.ErrCurrentAudio)),
+			code:    codes.FailedPrecondition,
+			reason:  grpcapi.ErrorReason_AUDIO_IS_CURRENT,
+			audioId: testAudioId,
+		},
+		{
+			name:    "version mismatch",
+			err:     withAudio(testAudioId, playlist.ErrVersionMismatch),
+			code:    codes.Aborted,
+			reason:  grpcapi.ErrorReason_AUDIO_VERSION_MISMATCH,
+			audioId: testAudioId,
+		},
+		{name: "not in trash", err: playlist.ErrNotInTrash, code: codes.NotFound, reason: grpcapi.ErrorReason_AUDIO_NOT_IN_TRASH},
+		{name: "nothing to undo", err: playlist.ErrNothingToUndo, code: codes.FailedPrecondition, reason: grpcapi.ErrorReason_NOTHING_TO_UNDO},
+		{name: "nothing to redo", err: playlist.ErrNothingToRedo, code: codes.FailedPrecondition, reason: grpcapi.ErrorReason_NOTHING_TO_REDO},
+		{name: "history disabled", err: playlist.ErrHistoryDisabled, code: codes.FailedPrecondition, reason: grpcapi.ErrorReason_HISTORY_DISABLED},
+		{name: "no audio", err: player.ErrNoAudio, code: codes.NotFound, reason: grpcapi.ErrorReason_NO_AUDIO},
+		{name: "player closed", err: player.ErrPlayerClosed, code: codes.Unavailable, reason: grpcapi.ErrorReason_PLAYER_CLOSED},
+		{name: "position out of range", err: player.ErrPositionOutOfRange, code: codes.OutOfRange, reason: grpcapi.ErrorReason_POSITION_OUT_OF_RANGE},
+		{name: "sleep timer in past", err: player.ErrSleepTimerInPast, code: codes.InvalidArgument, reason: grpcapi.ErrorReason_SLEEP_TIMER_IN_PAST},
+		{
+			name:       "queue full",
+			err:        player.ErrQueueFull,
+			code:       codes.ResourceExhausted,
+			reason:     grpcapi.ErrorReason_COMMAND_QUEUE_FULL,
+			retryDelay: queueFullRetryDelay,
+		},
+		{name: "deadline", err: context.DeadlineExceeded, code: codes.DeadlineExceeded, reason: grpcapi.ErrorReason_DEADLINE_EXCEEDED},
+		{name: "canceled", err: context.Canceled, code: codes.Canceled, reason: grpcapi.ErrorReason_CANCELED},
+		{name: "audit disabled", err: audit.ErrDisabled, code: codes.FailedPrecondition, reason: grpcapi.ErrorReason_AUDIT_DISABLED},
+		{name: "slow subscriber", err: events.ErrSlowSubscriber, code: codes.ResourceExhausted, reason: grpcapi.ErrorReason_SLOW_SUBSCRIBER},
+		{name: "bus closed", err: events.ErrClosed, code: codes.Unavailable, reason: grpcapi.ErrorReason_SHUTTING_DOWN},
+		{name: "unknown", err: errors.New("disk is full"), code: codes.Internal},
+		{name: "status", err: status.Error(codes.PermissionDenied, "denied"), code: codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatusError(tt.err))
+			if st.Code() != tt.code {
+				t.Errorf("code = %s, want %s", st.Code(), tt.code)
+			}
+			if _, ok := status.FromError(tt.err); !ok && st.Message() != tt.err.Error() {
+				t.Errorf("message = %q, want %q", st.Message(), tt.err.Error())
+			}
+
+			var (
+				info     *errdetails.ErrorInfo
+				resource *errdetails.ResourceInfo
+				retry    *errdetails.RetryInfo
+			)
+			for _, d := range st.Details() {
+				switch d := d.(type) {
+				case *errdetails.ErrorInfo:
+					info = d
+				case *errdetails.ResourceInfo:
+					resource = d
+				case *errdetails.RetryInfo:
+					retry = d
+				default:
+					t.Errorf("unexpected detail %T", d)
+				}
+			}
+
+			if tt.reason == grpcapi.ErrorReason_ERROR_REASON_UNSPECIFIED {
+				if info != nil {
+					t.Errorf("ErrorInfo = %v, want none", info)
+				}
+			} else if info.GetReason() != tt.reason.String() || info.GetDomain() != grpcapi.ErrorDomain {
+				t.Errorf("ErrorInfo = %v, want reason %s in %s", info, tt.reason, grpcapi.ErrorDomain)
+			}
+
+			if resource.GetResourceName() != tt.audioId {
+				t.Errorf("ResourceInfo = %v, want audio %q", resource, tt.audioId)
+			}
+			if tt.audioId != "" && resource.GetResourceType() != audioResourceType {
+				t.Errorf("resource type = %q, want %q", resource.GetResourceType(), audioResourceType)
+			}
+
+			if got := retry.GetRetryDelay().AsDuration(); got != tt.retryDelay {
+				t.Errorf("retry delay = %s, want %s", got, tt.retryDelay)
+			}
+		})
+	}
+}
+
+// TestToStatusError_Mappings checks that every reason is reported for exactly one error.
+func TestToStatusError_Mappings(t *testing.T) {
+	mapped := make(map[grpcapi.ErrorReason]bool)
+	for _, m := range errorMappings {
+		if mapped[m.reason] {
+			t.Errorf("reason %s is mapped twice", m.reason)
+		}
+		mapped[m.reason] = true
+	}
+	for value, name := range grpcapi.ErrorReason_name {
+		reason := grpcapi.ErrorReason(value)
+		if reason != grpcapi.ErrorReason_ERROR_REASON_UNSPECIFIED && !mapped[reason] {
+			t.Errorf("reason %s is not mapped from any error", name)
+		}
+	}
+}
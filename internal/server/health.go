@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/player"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const healthCheckInterval = 5 * time.Second
+
+// NewHealthServer creates a grpc.health.v1 server and updates its status
+// every healthCheckInterval until ctx is done. The status is SERVING
+// while the player is not closed and the playlist storage is writable.
+func NewHealthServer(ctx context.Context, p *player.Player, logger *slog.Logger) *health.Server {
+	hs := health.NewServer()
+
+	update := func() {
+		st := healthStatus(ctx, p, logger)
+		hs.SetServingStatus("", st)
+		hs.SetServingStatus(grpcapi.PlayerService_ServiceDesc.ServiceName, st)
+	}
+	update()
+
+	go func() {
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				hs.Shutdown()
+				return
+			case <-ticker.C:
+				update()
+			}
+		}
+	}()
+
+	return hs
+}
+
+func healthStatus(ctx context.Context, p *player.Player, logger *slog.Logger) healthpb.HealthCheckResponse_ServingStatus {
+	if p.IsClosed() {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	if c, ok := p.Playlist.(playlist.Checker); ok {
+		if err := c.Check(ctx); err != nil {
+			logger.Warn("playlist storage health check failed", slog.Any("error", err))
+			return healthpb.HealthCheckResponse_NOT_SERVING
+		}
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/logging"
+	"github.com/rs/xid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIdHeader is the metadata key of the request id.
+const RequestIdHeader = "x-request-id"
+
+// UnaryRequestIdInterceptor puts the request id from metadata or, if there is none,
+// a new one to the context and sends it back in the response header.
+func UnaryRequestIdInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := requestId(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIdHeader, id))
+		return handler(logging.WithRequestId(ctx, id), req)
+	}
+}
+
+// StreamRequestIdInterceptor is the stream counterpart of UnaryRequestIdInterceptor.
+func StreamRequestIdInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := requestId(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(RequestIdHeader, id))
+		return handler(srv, &requestIdStream{ServerStream: ss, ctx: logging.WithRequestId(ss.Context(), id)})
+	}
+}
+
+type requestIdStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *requestIdStream) Context() context.Context {
+	return s.ctx
+}
+
+// requestId returns the request id from metadata or a new one.
+func requestId(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get(RequestIdHeader); len(vals) > 0 && vals[0] != "" && len(vals[0]) <= 64 {
+			return vals[0]
+		}
+	}
+	return xid.New().String()
+}
+
+// UnaryLoggingInterceptor logs every RPC with its result and duration.
+func UnaryLoggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		attrs := []slog.Attr{
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		level := slog.LevelInfo
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+		}
+		logger.LogAttrs(ctx, level, "rpc handled", attrs...)
+		return resp, err
+	}
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Karzoug/gocloudcamp/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// headerStream keeps the header set by stream interceptors.
+type headerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *headerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// transportStream keeps the header set by unary interceptors.
+type transportStream struct {
+	header metadata.MD
+}
+
+func (s *transportStream) Method() string {
+	return "/grpcapi.PlayerService/Play"
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(metadata.MD) error {
+	return nil
+}
+
+func TestRequestIdInterceptors(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		// want is the expected id, a new one is expected if it is empty
+		want string
+	}{
+		{name: "from metadata", md: metadata.Pairs(RequestIdHeader, "req-1"), want: "req-1"},
+		{name: "no metadata"},
+		{name: "empty", md: metadata.Pairs(RequestIdHeader, "")},
+		{name: "too long", md: metadata.Pairs(RequestIdHeader, strings.Repeat("a", 65))},
+	}
+	for _, tt := range tests {
+		check := func(t *testing.T, ctx context.Context, header metadata.MD) {
+			t.Helper()
+			id := logging.RequestId(ctx)
+			if tt.want != "" && id != tt.want {
+				t.Errorf("request id = %q, want %q", id, tt.want)
+			}
+			if id == "" || len(id) > 64 {
+				t.Errorf("request id = %q, want a new one", id)
+			}
+			if got := header.Get(RequestIdHeader); len(got) != 1 || got[0] != id {
+				t.Errorf("header = %v, want %q", got, id)
+			}
+		}
+
+		t.Run("unary "+tt.name, func(t *testing.T) {
+			ss := &transportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(metadata.NewIncomingContext(context.Background(), tt.md), ss)
+			_, err := UnaryRequestIdInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+				check(t, ctx, ss.header)
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		t.Run("stream "+tt.name, func(t *testing.T) {
+			ss := &headerStream{ctx: metadata.NewIncomingContext(context.Background(), tt.md)}
+			err := StreamRequestIdInterceptor()(nil, ss, &grpc.StreamServerInfo{}, func(_ any, stream grpc.ServerStream) error {
+				check(t, stream.Context(), ss.header)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -2,79 +2,151 @@ package server
 
 import (
 	"context"
-	"errors"
+	"log/slog"
+	"strings"
+	"time"
 
-	"github.com/Karzoug/gocloudcamp/internal/grpcapi"
+	"github.com/Karzoug/gocloudcamp/internal/audit"
+	"github.com/Karzoug/gocloudcamp/internal/events"
 	"github.com/Karzoug/gocloudcamp/internal/models"
 	"github.com/Karzoug/gocloudcamp/internal/player"
 	"github.com/Karzoug/gocloudcamp/internal/playlist"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// server implements PlayerService. Handlers return domain errors as is,
+// they are translated to status errors by UnaryErrorInterceptor.
 type server struct {
 	grpcapi.PlayerServiceServer
-	player *player.Player
+	player  *player.Player
+	audit   *audit.Log
+	events  *events.Bus
+	history *playlist.History
+	logger  *slog.Logger
 }
 
-func New(p *player.Player) *server {
-	return &server{player: p}
+// Option configures the server.
+type Option func(*server)
+
+// WithAudit enables ListAuditEvents over the audit log.
+func WithAudit(l *audit.Log) Option {
+	return func(s *server) {
+		s.audit = l
+	}
+}
+
+// WithEvents makes the server publish playlist changes to the bus
+// and enables WatchEvents over it.
+func WithEvents(b *events.Bus) Option {
+	return func(s *server) {
+		s.events = b
+	}
+}
+
+// WithHistory enables Undo and Redo over the history,
+// which must be the playlist of the player or wrapped by it.
+func WithHistory(h *playlist.History) Option {
+	return func(s *server) {
+		s.history = h
+	}
+}
+
+func New(p *player.Player, logger *slog.Logger, opts ...Option) *server {
+	s := &server{player: p, logger: logger}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// readOnlyMethods are PlayerService methods that do not change the player or the playlist.
+var readOnlyMethods = map[string]bool{
+	"/grpcapi.PlayerService/GetStatus":       true,
+	"/grpcapi.PlayerService/GetSleepTimer":   true,
+	"/grpcapi.PlayerService/ReadAudio":       true,
+	"/grpcapi.PlayerService/ListAudio":       true,
+	"/grpcapi.PlayerService/ListTrash":       true,
+	"/grpcapi.PlayerService/ListAuditEvents": true,
+	"/grpcapi.PlayerService/WatchEvents":     true,
+}
+
+// IsMutating reports whether the method is a PlayerService method changing
+// the player or the playlist.
+func IsMutating(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpcapi.PlayerService/") && !readOnlyMethods[fullMethod]
 }
 
 func (s *server) Play(ctx context.Context, _ *grpcapi.PlayRequest) (*grpcapi.PlayResponse, error) {
-	err := s.player.Play(ctx)
-	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case errors.Is(err, player.ErrNoAudio):
-			return nil, status.Error(codes.NotFound, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+	if err := s.player.Play(ctx); err != nil {
+		return nil, err
 	}
 	return &grpcapi.PlayResponse{}, nil
 }
 func (s *server) Pause(ctx context.Context, _ *grpcapi.PauseRequest) (*grpcapi.PauseResponse, error) {
-	err := s.player.Pause(ctx)
-	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+	if err := s.player.Pause(ctx); err != nil {
+		return nil, err
 	}
-	return &grpcapi.PauseResponse{}, err
+	return &grpcapi.PauseResponse{}, nil
 }
 func (s *server) Next(ctx context.Context, _ *grpcapi.NextRequest) (*grpcapi.NextResponse, error) {
-	err := s.player.Next(ctx)
-	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case errors.Is(err, player.ErrNoAudio):
-			return nil, status.Error(codes.NotFound, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+	if err := s.player.Next(ctx); err != nil {
+		return nil, err
 	}
-	return &grpcapi.NextResponse{}, err
+	return &grpcapi.NextResponse{}, nil
 }
 func (s *server) Prev(ctx context.Context, _ *grpcapi.PrevRequest) (*grpcapi.PrevResponse, error) {
-	err := s.player.Prev(ctx)
+	if err := s.player.Prev(ctx); err != nil {
+		return nil, err
+	}
+	return &grpcapi.PrevResponse{}, nil
+}
+func (s *server) Seek(ctx context.Context, req *grpcapi.SeekRequest) (*grpcapi.SeekResponse, error) {
+	if err := s.player.Seek(ctx, req.GetPosition().AsDuration()); err != nil {
+		return nil, err
+	}
+	return &grpcapi.SeekResponse{}, nil
+}
+func (s *server) GetStatus(_ context.Context, _ *grpcapi.GetStatusRequest) (*grpcapi.GetStatusResponse, error) {
+	st := s.player.Status()
+	resp := grpcapi.GetStatusResponse{
+		State:    st.State.String(),
+		Position: durationpb.New(st.Position),
+	}
+	if st.Audio != nil {
+		resp.Audio = toProtoAudio(st.Audio)
+	}
+	return &resp, nil
+}
+func (s *server) SetSleepTimer(ctx context.Context, req *grpcapi.SetSleepTimerRequest) (*grpcapi.SetSleepTimerResponse, error) {
+	var (
+		t   *models.SleepTimer
+		err error
+	)
+	switch timer := req.GetTimer().(type) {
+	case *grpcapi.SetSleepTimerRequest_Duration:
+		t, err = s.player.SetSleepTimerAfter(ctx, timer.Duration.AsDuration())
+	case *grpcapi.SetSleepTimerRequest_Time:
+		t, err = s.player.SetSleepTimer(ctx, models.SleepTimer{At: timer.Time.AsTime()})
+	case *grpcapi.SetSleepTimerRequest_EndOfTrack:
+		t, err = s.player.SetSleepTimer(ctx, models.SleepTimer{Tracks: 1})
+	case *grpcapi.SetSleepTimerRequest_Tracks:
+		t, err = s.player.SetSleepTimer(ctx, models.SleepTimer{Tracks: int(timer.Tracks)})
+	}
 	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case errors.Is(err, player.ErrNoAudio):
-			return nil, status.Error(codes.NotFound, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+		return nil, err
 	}
-	return &grpcapi.PrevResponse{}, err
+	return &grpcapi.SetSleepTimerResponse{SleepTimer: toSleepTimer(t)}, nil
+}
+func (s *server) GetSleepTimer(_ context.Context, _ *grpcapi.GetSleepTimerRequest) (*grpcapi.GetSleepTimerResponse, error) {
+	return &grpcapi.GetSleepTimerResponse{SleepTimer: toSleepTimer(s.player.SleepTimer())}, nil
+}
+func (s *server) CancelSleepTimer(ctx context.Context, _ *grpcapi.CancelSleepTimerRequest) (*grpcapi.CancelSleepTimerResponse, error) {
+	if err := s.player.CancelSleepTimer(ctx); err != nil {
+		return nil, err
+	}
+	return &grpcapi.CancelSleepTimerResponse{}, nil
 }
 func (s *server) CreateAudio(ctx context.Context, req *grpcapi.CreateAudioRequest) (*grpcapi.CreateAudioResponse, error) {
 	reqAudio := req.GetAudio()
@@ -84,105 +156,294 @@ func (s *server) CreateAudio(ctx context.Context, req *grpcapi.CreateAudioReques
 		Duration: reqAudio.GetDuration().AsDuration(),
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case err == playlist.ErrNotFound:
-			return nil, status.Error(codes.NotFound, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+		return nil, err
 	}
+	s.logger.InfoContext(ctx, "audio created", slog.String("id", respAudio.Id))
+	s.events.Publish(events.Event{Type: events.AudioCreated, Audio: respAudio})
 	return &grpcapi.CreateAudioResponse{
-		Audio: &grpcapi.Audio{
-			Id:       respAudio.Id,
-			Name:     respAudio.Name,
-			Duration: durationpb.New(respAudio.Duration),
-		},
+		Audio: toProtoAudio(respAudio),
 	}, nil
 }
 func (s *server) ReadAudio(ctx context.Context, req *grpcapi.ReadAudioRequest) (*grpcapi.ReadAudioResponse, error) {
 	reqAudioId := req.GetId()
 	respAudio, err := s.player.Playlist.Get(ctx, reqAudioId)
 	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case err == playlist.ErrNotFound:
-			return nil, status.Error(codes.NotFound, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+		return nil, withAudio(reqAudioId, err)
 	}
 	return &grpcapi.ReadAudioResponse{
-		Audio: &grpcapi.Audio{
-			Id:       respAudio.Id,
-			Name:     respAudio.Name,
-			Duration: durationpb.New(respAudio.Duration),
-		},
+		Audio: toProtoAudio(respAudio),
 	}, nil
 }
 func (s *server) UpdateAudio(ctx context.Context, req *grpcapi.UpdateAudioRequest) (*grpcapi.UpdateAudioResponse, error) {
 	reqAudio := req.GetAudio()
-	respAudio, err := s.player.Playlist.Update(ctx, models.Audio{
+	respAudio, err := s.player.UpdateAudio(ctx, models.Audio{
 		Id:       reqAudio.GetId(),
 		Name:     reqAudio.GetName(),
 		Duration: reqAudio.GetDuration().AsDuration(),
+		Version:  req.GetExpectedVersion(),
 	})
 	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case errors.Is(err, playlist.ErrNotFound):
-			return nil, status.Error(codes.NotFound, err.Error())
-		case errors.Is(err, playlist.ErrCurrentAudio):
-			return nil, status.Error(codes.FailedPrecondition, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+		return nil, withAudio(reqAudio.GetId(), err)
 	}
+	s.events.Publish(events.Event{Type: events.AudioUpdated, Audio: respAudio})
 	return &grpcapi.UpdateAudioResponse{
-		Audio: &grpcapi.Audio{
-			Id:       respAudio.Id,
-			Name:     respAudio.Name,
-			Duration: durationpb.New(respAudio.Duration),
-		},
+		Audio: toProtoAudio(respAudio),
 	}, nil
 }
 func (s *server) DeleteAudio(ctx context.Context, req *grpcapi.DeleteAudioRequest) (*grpcapi.DeleteAudioResponse, error) {
 	reqAudioId := req.GetId()
-	err := s.player.Playlist.Delete(ctx, reqAudioId)
-	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		case errors.Is(err, playlist.ErrCurrentAudio):
-			return nil, status.Error(codes.FailedPrecondition, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+	if err := s.player.DeleteAudio(ctx, reqAudioId, req.GetExpectedVersion()); err != nil {
+		return nil, withAudio(reqAudioId, err)
 	}
+	s.logger.InfoContext(ctx, "audio deleted", slog.String("id", reqAudioId))
+	s.events.Publish(events.Event{Type: events.AudioDeleted, AudioId: reqAudioId})
 	return &grpcapi.DeleteAudioResponse{}, nil
 }
 func (s *server) ListAudio(ctx context.Context, _ *grpcapi.ListAudioRequest) (*grpcapi.ListAudioResponse, error) {
-	slice, err := s.player.Playlist.List(ctx)
+	slice, revision, err := s.player.Playlist.List(ctx)
 	if err != nil {
-		switch {
-		case errors.Is(err, context.DeadlineExceeded):
-			return nil, status.Error(codes.DeadlineExceeded, err.Error())
-		default:
-			return nil, status.Error(codes.Internal, err.Error())
-		}
+		return nil, err
 	}
 	resp := grpcapi.ListAudioResponse{
-		Audio: []*grpcapi.Audio{},
+		Audio:    []*grpcapi.Audio{},
+		Revision: revision,
+	}
+	for _, a := range slice {
+		resp.Audio = append(resp.Audio, toProtoAudio(&a))
+	}
+	return &resp, nil
+}
+func (s *server) MoveAudio(ctx context.Context, req *grpcapi.MoveAudioRequest) (*grpcapi.MoveAudioResponse, error) {
+	reqAudioId := req.GetId()
+	if err := s.player.Playlist.Move(ctx, reqAudioId, int(req.GetIndex())); err != nil {
+		return nil, withAudio(reqAudioId, err)
+	}
+	s.events.Publish(events.Event{Type: events.AudioMoved, AudioId: reqAudioId, Index: int(req.GetIndex())})
+	return &grpcapi.MoveAudioResponse{}, nil
+}
+func (s *server) ListTrash(ctx context.Context, _ *grpcapi.ListTrashRequest) (*grpcapi.ListTrashResponse, error) {
+	slice, err := s.player.Playlist.ListTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := grpcapi.ListTrashResponse{
+		Audios: make([]*grpcapi.DeletedAudio, 0, len(slice)),
 	}
 	for _, a := range slice {
-		resp.Audio = append(resp.Audio, &grpcapi.Audio{
-			Id:       a.Id,
-			Name:     a.Name,
-			Duration: durationpb.New(a.Duration),
+		resp.Audios = append(resp.Audios, &grpcapi.DeletedAudio{
+			Audio:      toProtoAudio(&a.Audio),
+			Index:      int32(a.Index),
+			DeleteTime: timestamppb.New(a.DeletedAt),
+			ExpireTime: timestamppb.New(a.ExpiresAt),
+		})
+	}
+	return &resp, nil
+}
+func (s *server) RestoreAudio(ctx context.Context, req *grpcapi.RestoreAudioRequest) (*grpcapi.RestoreAudioResponse, error) {
+	reqAudioId := req.GetId()
+	respAudio, index, err := s.player.Playlist.Restore(ctx, reqAudioId)
+	if err != nil {
+		return nil, withAudio(reqAudioId, err)
+	}
+	s.logger.InfoContext(ctx, "audio restored", slog.String("id", reqAudioId), slog.Int("index", index))
+	s.events.Publish(events.Event{Type: events.AudioRestored, Audio: respAudio, Index: index})
+	return &grpcapi.RestoreAudioResponse{
+		Audio: toProtoAudio(respAudio),
+		Index: int32(index),
+	}, nil
+}
+func (s *server) PurgeTrash(ctx context.Context, req *grpcapi.PurgeTrashRequest) (*grpcapi.PurgeTrashResponse, error) {
+	n, err := s.player.Playlist.PurgeTrash(ctx, req.GetIds()...)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "trash purged", slog.Int("purged", n))
+	return &grpcapi.PurgeTrashResponse{Purged: int32(n)}, nil
+}
+func (s *server) Undo(ctx context.Context, req *grpcapi.UndoRequest) (*grpcapi.UndoResponse, error) {
+	if s.history == nil {
+		return nil, playlist.ErrHistoryDisabled
+	}
+	c, err := s.history.Undo(ctx, req.GetGlobal())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "change undone", slog.String("operation", c.Op.String()))
+	s.publishChange(c)
+	return &grpcapi.UndoResponse{Change: toChange(c)}, nil
+}
+func (s *server) Redo(ctx context.Context, req *grpcapi.RedoRequest) (*grpcapi.RedoResponse, error) {
+	if s.history == nil {
+		return nil, playlist.ErrHistoryDisabled
+	}
+	c, err := s.history.Redo(ctx, req.GetGlobal())
+	if err != nil {
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "change redone", slog.String("operation", c.Op.String()))
+	s.publishChange(c)
+	return &grpcapi.RedoResponse{Change: toChange(c)}, nil
+}
+
+// publishChange publishes the event a change applied by Undo or Redo would have
+// been published with if it was made directly.
+func (s *server) publishChange(c playlist.Change) {
+	switch c.Op {
+	case playlist.OpDelete:
+		s.events.Publish(events.Event{Type: events.AudioDeleted, AudioId: c.Audio.Id})
+	case playlist.OpRestore:
+		s.events.Publish(events.Event{Type: events.AudioRestored, Audio: c.Audio, Index: c.Index})
+	case playlist.OpUpdate:
+		s.events.Publish(events.Event{Type: events.AudioUpdated, Audio: c.Audio})
+	case playlist.OpMove:
+		s.events.Publish(events.Event{Type: events.AudioMoved, AudioId: c.Audio.Id, Index: c.Index})
+	}
+}
+
+func toProtoAudio(a *models.Audio) *grpcapi.Audio {
+	return &grpcapi.Audio{
+		Id:       a.Id,
+		Name:     a.Name,
+		Duration: durationpb.New(a.Duration),
+		Version:  a.Version,
+	}
+}
+
+func toChange(c playlist.Change) *grpcapi.Change {
+	resp := &grpcapi.Change{
+		Operation: c.Op.String(),
+		Index:     int32(c.Index),
+	}
+	if c.Audio != nil {
+		resp.Audio = toProtoAudio(c.Audio)
+	}
+	return resp
+}
+
+// toSleepTimer returns nil if there is no sleep timer.
+func toSleepTimer(t *models.SleepTimer) *grpcapi.SleepTimer {
+	if t == nil {
+		return nil
+	}
+	resp := &grpcapi.SleepTimer{Tracks: int32(t.Tracks)}
+	if !t.At.IsZero() {
+		resp.FireTime = timestamppb.New(t.At)
+	}
+	return resp
+}
+
+func (s *server) ListAuditEvents(_ context.Context, req *grpcapi.ListAuditEventsRequest) (*grpcapi.ListAuditEventsResponse, error) {
+	if s.audit == nil {
+		return nil, audit.ErrDisabled
+	}
+	f := audit.Filter{
+		Principal: req.GetPrincipal(),
+		Method:    req.GetMethod(),
+		Limit:     int(req.GetLimit()),
+	}
+	if req.GetFrom() != nil {
+		f.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		f.To = req.GetTo().AsTime()
+	}
+	events, err := s.audit.Query(f)
+	if err != nil {
+		return nil, err
+	}
+	resp := grpcapi.ListAuditEventsResponse{
+		Events: make([]*grpcapi.AuditEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, &grpcapi.AuditEvent{
+			Id:        e.Id,
+			Time:      timestamppb.New(e.Time),
+			Principal: e.Principal,
+			Peer:      e.Peer,
+			Method:    e.Method,
+			Request:   e.Request,
+			Code:      e.Code,
+			Error:     e.Error,
 		})
 	}
 	return &resp, nil
 }
+
+func (s *server) WatchEvents(req *grpcapi.WatchEventsRequest, stream grpcapi.PlayerService_WatchEventsServer) error {
+	if s.events == nil {
+		return grpcapi.UnimplementedPlayerServiceServer{}.WatchEvents(req, stream)
+	}
+
+	// the request is validated by StreamValidationInterceptor
+	types := make([]events.Type, 0, len(req.GetTypes()))
+	for _, t := range req.GetTypes() {
+		types = append(types, eventTypes[t])
+	}
+	var heartbeats <-chan time.Time
+	if req.GetHeartbeatInterval() != nil {
+		ticker := time.NewTicker(req.GetHeartbeatInterval().AsDuration())
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+
+	sub, missed, lost := s.events.Subscribe(types, req.GetAfterId())
+	defer sub.Close()
+
+	if lost {
+		if err := stream.Send(&grpcapi.Event{Type: grpcapi.EventType_EVENTS_LOST, Time: timestamppb.Now()}); err != nil {
+			return err
+		}
+	}
+	for _, e := range missed {
+		if err := stream.Send(toEvent(e)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-sub.Events():
+			if !ok {
+				return sub.Err()
+			}
+			if err := stream.Send(toEvent(e)); err != nil {
+				return err
+			}
+		case <-heartbeats:
+			if err := stream.Send(&grpcapi.Event{Type: grpcapi.EventType_HEARTBEAT, Time: timestamppb.Now()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var eventTypes = map[grpcapi.EventType]events.Type{
+	grpcapi.EventType_STATE_CHANGED:  events.StateChanged,
+	grpcapi.EventType_TRACK_CHANGED:  events.TrackChanged,
+	grpcapi.EventType_AUDIO_CREATED:  events.AudioCreated,
+	grpcapi.EventType_AUDIO_UPDATED:  events.AudioUpdated,
+	grpcapi.EventType_AUDIO_DELETED:  events.AudioDeleted,
+	grpcapi.EventType_AUDIO_MOVED:    events.AudioMoved,
+	grpcapi.EventType_AUDIO_RESTORED: events.AudioRestored,
+}
+
+func toEvent(e events.Event) *grpcapi.Event {
+	resp := &grpcapi.Event{
+		Id:      e.Id,
+		Time:    timestamppb.New(e.Time),
+		State:   e.State,
+		AudioId: e.AudioId,
+		Index:   int32(e.Index),
+	}
+	for t, et := range eventTypes {
+		if et == e.Type {
+			resp.Type = t
+			break
+		}
+	}
+	if e.Audio != nil {
+		resp.Audio = toProtoAudio(e.Audio)
+	}
+	return resp
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/rs/xid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxAudioNameLength = 256
+	minAudioDuration   = time.Second
+	maxAudioDuration   = 24 * time.Hour
+
+	minHeartbeatInterval = time.Second
+
+	maxMetadataKeys        = 64
+	maxMetadataValueLength = 4096
+)
+
+// violations collects field violations of a request.
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, format string, args ...any) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns nil if there are no violations, otherwise
+// InvalidArgument status error with BadRequest details.
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	st := status.New(codes.InvalidArgument, fmt.Sprintf("invalid request: %s: %s", v[0].Field, v[0].Description))
+	if dst, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = dst
+	}
+	return st.Err()
+}
+
+// UnaryValidationInterceptor rejects requests with invalid fields or metadata.
+func UnaryValidationInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var v violations
+		validateMetadata(ctx, &v)
+		validateRequest(req, &v)
+		if err := v.err(); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidationInterceptor rejects streams with invalid metadata
+// and received messages with invalid fields.
+func StreamValidationInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		var v violations
+		validateMetadata(ss.Context(), &v)
+		if err := v.err(); err != nil {
+			return err
+		}
+		return handler(srv, &validatingStream{ServerStream: ss})
+	}
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	var v violations
+	validateRequest(m, &v)
+	return v.err()
+}
+
+func validateRequest(req any, v *violations) {
+	switch r := req.(type) {
+	case *grpcapi.CreateAudioRequest:
+		if r.GetAudio() == nil {
+			v.add("audio", "must be set")
+			return
+		}
+		if r.GetAudio().GetId() != "" {
+			v.add("audio.id", "must be empty, id is assigned by the server")
+		}
+		validateAudio("audio", r.GetAudio(), v)
+	case *grpcapi.ReadAudioRequest:
+		validateId("id", r.GetId(), v)
+	case *grpcapi.UpdateAudioRequest:
+		if r.GetAudio() == nil {
+			v.add("audio", "must be set")
+			return
+		}
+		validateId("audio.id", r.GetAudio().GetId(), v)
+		validateAudio("audio", r.GetAudio(), v)
+	case *grpcapi.DeleteAudioRequest:
+		validateId("id", r.GetId(), v)
+	case *grpcapi.MoveAudioRequest:
+		validateId("id", r.GetId(), v)
+		if r.GetIndex() < 0 {
+			v.add("index", "must not be negative")
+		}
+	case *grpcapi.RestoreAudioRequest:
+		validateId("id", r.GetId(), v)
+	case *grpcapi.PurgeTrashRequest:
+		for i, id := range r.GetIds() {
+			validateId(fmt.Sprintf("ids[%d]", i), id, v)
+		}
+	case *grpcapi.SeekRequest:
+		switch pos := r.GetPosition(); {
+		case pos == nil:
+			v.add("position", "must be set")
+		case pos.CheckValid() != nil:
+			v.add("position", "must be a valid duration")
+		case pos.AsDuration() < 0:
+			v.add("position", "must not be negative")
+		}
+	case *grpcapi.SetSleepTimerRequest:
+		switch timer := r.GetTimer().(type) {
+		case nil:
+			v.add("timer", "one of duration, time, end_of_track or tracks must be set")
+		case *grpcapi.SetSleepTimerRequest_Duration:
+			if timer.Duration.CheckValid() != nil {
+				v.add("duration", "must be a valid duration")
+			} else if timer.Duration.AsDuration() <= 0 {
+				v.add("duration", "must be positive")
+			}
+		case *grpcapi.SetSleepTimerRequest_Time:
+			if timer.Time.CheckValid() != nil {
+				v.add("time", "must be a valid timestamp")
+			}
+		case *grpcapi.SetSleepTimerRequest_EndOfTrack:
+			if !timer.EndOfTrack {
+				v.add("end_of_track", "must be true")
+			}
+		case *grpcapi.SetSleepTimerRequest_Tracks:
+			if timer.Tracks <= 0 {
+				v.add("tracks", "must be positive")
+			}
+		}
+	case *grpcapi.WatchEventsRequest:
+		for i, t := range r.GetTypes() {
+			if _, ok := eventTypes[t]; !ok {
+				v.add(fmt.Sprintf("types[%d]", i), "unknown event type %s", t)
+			}
+		}
+		switch interval := r.GetHeartbeatInterval(); {
+		case interval == nil:
+		case interval.CheckValid() != nil:
+			v.add("heartbeat_interval", "must be a valid duration")
+		case interval.AsDuration() < minHeartbeatInterval:
+			v.add("heartbeat_interval", "must be at least %s", minHeartbeatInterval)
+		}
+	case *grpcapi.ListAuditEventsRequest:
+		if r.GetLimit() < 0 {
+			v.add("limit", "must not be negative")
+		}
+		if r.GetFrom() != nil && r.GetFrom().CheckValid() != nil {
+			v.add("from", "must be a valid timestamp")
+		}
+		if r.GetTo() != nil && r.GetTo().CheckValid() != nil {
+			v.add("to", "must be a valid timestamp")
+		}
+	}
+}
+
+func validateAudio(field string, a *grpcapi.Audio, v *violations) {
+	switch name := a.GetName(); {
+	case name == "":
+		v.add(field+".name", "must not be empty")
+	case !utf8.ValidString(name):
+		v.add(field+".name", "must be a valid UTF-8 string")
+	case utf8.RuneCountInString(name) > maxAudioNameLength:
+		v.add(field+".name", "must be at most %d characters long", maxAudioNameLength)
+	}
+
+	if a.GetDuration() == nil {
+		v.add(field+".duration", "must be set")
+		return
+	}
+	if err := a.GetDuration().CheckValid(); err != nil {
+		v.add(field+".duration", "must be a valid duration")
+		return
+	}
+	if d := a.GetDuration().AsDuration(); d < minAudioDuration || d > maxAudioDuration {
+		v.add(field+".duration", "must be between %s and %s", minAudioDuration, maxAudioDuration)
+	}
+}
+
+func validateId(field, id string, v *violations) {
+	if id == "" {
+		v.add(field, "must not be empty")
+		return
+	}
+	if _, err := xid.FromString(id); err != nil {
+		v.add(field, "must be a valid audio id")
+	}
+}
+
+func validateMetadata(ctx context.Context, v *violations) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return
+	}
+	if len(md) > maxMetadataKeys {
+		v.add("metadata", "must contain at most %d keys", maxMetadataKeys)
+	}
+	for k, vals := range md {
+		for _, val := range vals {
+			if len(val) > maxMetadataValueLength {
+				v.add("metadata."+k, "value must be at most %d bytes long", maxMetadataValueLength)
+				break
+			}
+		}
+	}
+}
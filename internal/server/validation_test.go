@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testAudioId = "cn0000000000000000a0"
+
+func TestUnaryValidationInterceptor(t *testing.T) {
+	audio := func(name string, d time.Duration) *grpcapi.Audio {
+		return &grpcapi.Audio{Name: name, Duration: durationpb.New(d)}
+	}
+	invalidDuration := &durationpb.Duration{Seconds: 1, Nanos: -1}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  any
+		// fields are the fields of the expected violations, none if the request is valid
+		fields []string
+	}{
+		{name: "create", req: &grpcapi.CreateAudioRequest{Audio: audio("intro", time.Minute)}},
+		{name: "create without audio", req: &grpcapi.CreateAudioRequest{}, fields: []string{"audio"}},
+		{
+			name:   "create with id",
+			req:    &grpcapi.CreateAudioRequest{Audio: &grpcapi.Audio{Id: testAudioId, Name: "intro", Duration: durationpb.New(time.Minute)}},
+			fields: []string{"audio.id"},
+		},
+		{name: "empty name", req: &grpcapi.CreateAudioRequest{Audio: audio("", time.Minute)}, fields: []string{"audio.name"}},
+		{name: "invalid UTF-8 name", req: &grpcapi.CreateAudioRequest{Audio: audio("\xff", time.Minute)}, fields: []string{"audio.name"}},
+		{name: "long name", req: &grpcapi.CreateAudioRequest{Audio: audio(strings.Repeat("я", maxAudioNameLength+1), time.Minute)}, fields: []string{"audio.name"}},
+		{name: "longest name", req: &grpcapi.CreateAudioRequest{Audio: audio(strings.Repeat("я", maxAudioNameLength), time.Minute)}},
+		{name: "no duration", req: &grpcapi.CreateAudioRequest{Audio: &grpcapi.Audio{Name: "intro"}}, fields: []string{"audio.duration"}},
+		{name: "bad duration", req: &grpcapi.CreateAudioRequest{Audio: &grpcapi.Audio{Name: "intro", Duration: invalidDuration}}, fields: []string{"audio.duration"}},
+		{name: "short duration", req: &grpcapi.CreateAudioRequest{Audio: audio("intro", time.Millisecond)}, fields: []string{"audio.duration"}},
+		{name: "long duration", req: &grpcapi.CreateAudioRequest{Audio: audio("intro", 25*time.Hour)}, fields: []string{"audio.duration"}},
+		{name: "all audio fields", req: &grpcapi.CreateAudioRequest{Audio: &grpcapi.Audio{Id: testAudioId}}, fields: []string{"audio.id", "audio.name", "audio.duration"}},
+		{name: "read", req: &grpcapi.ReadAudioRequest{Id: testAudioId}},
+		{name: "read empty id", req: &grpcapi.ReadAudioRequest{}, fields: []string{"id"}},
+		{name: "read bad id", req: &grpcapi.ReadAudioRequest{Id: "42"}, fields: []string{"id"}},
+		{name: "update", req: &grpcapi.UpdateAudioRequest{Audio: &grpcapi.Audio{Id: testAudioId, Name: "intro", Duration: durationpb.New(time.Minute)}}},
+		{name: "update without audio", req: &grpcapi.UpdateAudioRequest{}, fields: []string{"audio"}},
+		{name: "update without id", req: &grpcapi.UpdateAudioRequest{Audio: audio("intro", time.Minute)}, fields: []string{"audio.id"}},
+		{name: "delete empty id", req: &grpcapi.DeleteAudioRequest{}, fields: []string{"id"}},
+		{name: "move", req: &grpcapi.MoveAudioRequest{Id: testAudioId, Index: 100}},
+		{name: "move to negative index", req: &grpcapi.MoveAudioRequest{Id: testAudioId, Index: -1}, fields: []string{"index"}},
+		{name: "move bad id and index", req: &grpcapi.MoveAudioRequest{Id: "42", Index: -1}, fields: []string{"id", "index"}},
+		{name: "restore bad id", req: &grpcapi.RestoreAudioRequest{Id: "42"}, fields: []string{"id"}},
+		{name: "purge all", req: &grpcapi.PurgeTrashRequest{}},
+		{name: "purge bad ids", req: &grpcapi.PurgeTrashRequest{Ids: []string{testAudioId, "", "42"}}, fields: []string{"ids[1]", "ids[2]"}},
+		{name: "seek", req: &grpcapi.SeekRequest{Position: durationpb.New(0)}},
+		{name: "seek without position", req: &grpcapi.SeekRequest{}, fields: []string{"position"}},
+		{name: "seek bad position", req: &grpcapi.SeekRequest{Position: invalidDuration}, fields: []string{"position"}},
+		{name: "seek negative position", req: &grpcapi.SeekRequest{Position: durationpb.New(-time.Second)}, fields: []string{"position"}},
+		{name: "sleep without timer", req: &grpcapi.SetSleepTimerRequest{}, fields: []string{"timer"}},
+		{
+			name:   "sleep zero duration",
+			req:    &grpcapi.SetSleepTimerRequest{Timer: &grpcapi.SetSleepTimerRequest_Duration{Duration: durationpb.New(0)}},
+			fields: []string{"duration"},
+		},
+		{
+			name:   "sleep bad duration",
+			req:    &grpcapi.SetSleepTimerRequest{Timer: &grpcapi.SetSleepTimerRequest_Duration{Duration: invalidDuration}},
+			fields: []string{"duration"},
+		},
+		{
+			name:   "sleep bad time",
+			req:    &grpcapi.SetSleepTimerRequest{Timer: &grpcapi.SetSleepTimerRequest_Time{Time: &timestamppb.Timestamp{Nanos: -1}}},
+			fields: []string{"time"},
+		},
+		{name: "sleep end of track", req: &grpcapi.SetSleepTimerRequest{Timer: &grpcapi.SetSleepTimerRequest_EndOfTrack{EndOfTrack: true}}},
+		{
+			name:   "sleep end of track false",
+			req:    &grpcapi.SetSleepTimerRequest{Timer: &grpcapi.SetSleepTimerRequest_EndOfTrack{}},
+			fields: []string{"end_of_track"},
+		},
+		{name: "sleep no tracks", req: &grpcapi.SetSleepTimerRequest{Timer: &grpcapi.SetSleepTimerRequest_Tracks{}}, fields: []string{"tracks"}},
+		{
+			name:   "audit negative limit and bad range",
+			req:    &grpcapi.ListAuditEventsRequest{Limit: -1, From: &timestamppb.Timestamp{Nanos: -1}, To: &timestamppb.Timestamp{Nanos: -1}},
+			fields: []string{"limit", "from", "to"},
+		},
+		{name: "request without rules", req: &grpcapi.PlayRequest{}},
+		{
+			name:   "long metadata value",
+			ctx:    metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-note", strings.Repeat("a", maxMetadataValueLength+1))),
+			req:    &grpcapi.PlayRequest{},
+			fields: []string{"metadata.x-note"},
+		},
+	}
+	interceptor := UnaryValidationInterceptor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			handled := false
+			_, err := interceptor(ctx, tt.req, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+				handled = true
+				return nil, nil
+			})
+			if len(tt.fields) == 0 {
+				if err != nil || !handled {
+					t.Fatalf("error = %v, handled = %v, want the request passed", err, handled)
+				}
+				return
+			}
+
+			if handled {
+				t.Error("invalid request is passed to the handler")
+			}
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Fatalf("code = %s, want %s", st.Code(), codes.InvalidArgument)
+			}
+			var fields []string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, fv := range br.GetFieldViolations() {
+						fields = append(fields, fv.GetField())
+						if fv.GetDescription() == "" {
+							t.Errorf("violation of %s has no description", fv.GetField())
+						}
+					}
+				}
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("violations of %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+// recvStream receives msg once.
+type recvStream struct {
+	grpc.ServerStream
+	msg *grpcapi.WatchEventsRequest
+}
+
+func (s *recvStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *recvStream) RecvMsg(m any) error {
+	proto.Merge(m.(proto.Message), s.msg)
+	return nil
+}
+
+func TestStreamValidationInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *grpcapi.WatchEventsRequest
+		want codes.Code
+	}{
+		{name: "empty", req: &grpcapi.WatchEventsRequest{}, want: codes.OK},
+		{
+			name: "valid",
+			req: &grpcapi.WatchEventsRequest{
+				Types:             []grpcapi.EventType{grpcapi.EventType_STATE_CHANGED, grpcapi.EventType_AUDIO_MOVED},
+				HeartbeatInterval: durationpb.New(time.Minute),
+			},
+			want: codes.OK,
+		},
+		{
+			name: "unknown type",
+			req:  &grpcapi.WatchEventsRequest{Types: []grpcapi.EventType{grpcapi.EventType_HEARTBEAT}},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "short heartbeat",
+			req:  &grpcapi.WatchEventsRequest{HeartbeatInterval: durationpb.New(time.Millisecond)},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "invalid heartbeat",
+			req:  &grpcapi.WatchEventsRequest{HeartbeatInterval: &durationpb.Duration{Seconds: 1, Nanos: -1}},
+			want: codes.InvalidArgument,
+		},
+	}
+	interceptor := StreamValidationInterceptor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := interceptor(nil, &recvStream{msg: tt.req}, &grpc.StreamServerInfo{}, func(_ any, ss grpc.ServerStream) error {
+				return ss.RecvMsg(new(grpcapi.WatchEventsRequest))
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %s, want %s (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
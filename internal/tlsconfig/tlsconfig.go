@@ -0,0 +1,134 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/filewatch"
+)
+
+const reloadInterval = 5 * time.Second
+
+type serverConfig interface {
+	TLSCertFile() string
+	TLSKeyFile() string
+	// TLSClientCAFile returns the CA bundle to verify client certificates,
+	// empty value disables client certificates verification.
+	TLSClientCAFile() string
+}
+
+// Server provides server TLS config with certificates
+// reloaded when their files change.
+type Server struct {
+	cfg     serverConfig
+	current atomic.Pointer[tls.Config]
+	logger  *slog.Logger
+}
+
+func NewServer(cfg serverConfig, logger *slog.Logger) (*Server, error) {
+	s := &Server{
+		cfg:    cfg,
+		logger: logger,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload loads certificates again. The current ones
+// are kept if the files cannot be loaded.
+func (s *Server) Reload() error {
+	cert, err := tls.LoadX509KeyPair(s.cfg.TLSCertFile(), s.cfg.TLSKeyFile())
+	if err != nil {
+		return fmt.Errorf("load server certificate error: %w", err)
+	}
+	c := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if s.cfg.TLSClientCAFile() != "" {
+		pool, err := loadCertPool(s.cfg.TLSClientCAFile())
+		if err != nil {
+			return fmt.Errorf("load client CA error: %w", err)
+		}
+		c.ClientCAs = pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	s.current.Store(c)
+	return nil
+}
+
+// Config returns TLS config for a server, every handshake
+// uses the most recently loaded certificates.
+func (s *Server) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return s.current.Load(), nil
+		},
+	}
+}
+
+// Watch reloads certificates every time their files change, until ctx is done.
+func (s *Server) Watch(ctx context.Context) {
+	s.watch(ctx, reloadInterval)
+}
+
+func (s *Server) watch(ctx context.Context, interval time.Duration) {
+	files := []string{s.cfg.TLSCertFile(), s.cfg.TLSKeyFile()}
+	if s.cfg.TLSClientCAFile() != "" {
+		files = append(files, s.cfg.TLSClientCAFile())
+	}
+	filewatch.Watch(ctx, interval, func() {
+		if err := s.Reload(); err != nil {
+			s.logger.Error("reload TLS certificates error", slog.Any("error", err))
+			return
+		}
+		s.logger.Info("TLS certificates reloaded")
+	}, files...)
+}
+
+// Client returns TLS config for a client. If caFile is empty, system roots are used,
+// if certFile and keyFile are set, the client certificate is presented to the server.
+func Client(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	c := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("load CA error: %w", err)
+		}
+		c.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate error: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+func loadCertPool(filename string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates found in " + filename)
+	}
+	return pool, nil
+}
@@ -0,0 +1,268 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate generated at test time.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+var serial int64
+
+// newCert returns a certificate with the common name signed by parent,
+// or a self-signed CA certificate if parent is nil.
+func newCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// write writes the certificate and the key to PEM files in dir.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", c.cert.Raw)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, filename, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testServerConfig struct {
+	certFile, keyFile, clientCAFile string
+}
+
+func (c testServerConfig) TLSCertFile() string     { return c.certFile }
+func (c testServerConfig) TLSKeyFile() string      { return c.keyFile }
+func (c testServerConfig) TLSClientCAFile() string { return c.clientCAFile }
+
+// handshake connects a client with cfg to the server and returns
+// the connection states seen by the client and by the server.
+func handshake(t *testing.T, srv *Server, cfg *tls.Config) (client, server tls.ConnectionState, err error) {
+	t.Helper()
+	c, s := net.Pipe()
+	defer c.Close()
+	defer s.Close()
+
+	serverConn := tls.Server(s, srv.Config())
+	serverErr := make(chan error, 1)
+	go func() {
+		err := serverConn.Handshake()
+		if err != nil {
+			// unblock the client waiting for the server response
+			s.Close()
+		}
+		serverErr <- err
+	}()
+	clientConn := tls.Client(c, cfg)
+	if err := clientConn.Handshake(); err != nil {
+		return client, server, err
+	}
+	// TLS 1.3 clients finish the handshake before the server verifies
+	// the client certificate, a read returns the server's verdict
+	go io.Copy(io.Discard, clientConn)
+	if err := <-serverErr; err != nil {
+		return client, server, err
+	}
+	return clientConn.ConnectionState(), serverConn.ConnectionState(), nil
+}
+
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "ca", nil)
+	otherCA := newCert(t, "other ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newCert(t, "player.test", ca).write(t, dir, "server")
+	aliceCertFile, aliceKeyFile := newCert(t, "alice", ca).write(t, dir, "alice")
+	malloryCertFile, malloryKeyFile := newCert(t, "mallory", otherCA).write(t, dir, "mallory")
+
+	srv, err := NewServer(testServerConfig{certFile: certFile, keyFile: keyFile, clientCAFile: caFile}, newLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		certFile, keyFile string
+		subject           string
+		wantErr           bool
+	}{
+		{name: "client certificate", certFile: aliceCertFile, keyFile: aliceKeyFile, subject: "alice"},
+		{name: "no client certificate", wantErr: true},
+		{name: "client certificate of other CA", certFile: malloryCertFile, keyFile: malloryKeyFile, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Client(caFile, tt.certFile, tt.keyFile, "player.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, state, err := handshake(t, srv, cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(state.VerifiedChains) == 0 || state.VerifiedChains[0][0].Subject.CommonName != tt.subject {
+				t.Errorf("got verified chains %v, want the leaf of %s", state.VerifiedChains, tt.subject)
+			}
+		})
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	first := newCert(t, "player.test", ca)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	srv, err := NewServer(testServerConfig{certFile: certFile, keyFile: keyFile}, newLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Client(caFile, "", "", "player.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverSerial := func() *big.Int {
+		t.Helper()
+		state, _, err := handshake(t, srv, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.PeerCertificates[0].SerialNumber
+	}
+
+	if got := serverSerial(); got.Cmp(first.cert.SerialNumber) != 0 {
+		t.Fatalf("got serial %s, want %s", got, first.cert.SerialNumber)
+	}
+
+	second := newCert(t, "player.test", ca)
+	second.write(t, dir, "server")
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := serverSerial(); got.Cmp(second.cert.SerialNumber) != 0 {
+		t.Fatalf("got serial %s after reload, want %s", got, second.cert.SerialNumber)
+	}
+
+	// broken files keep the current certificate
+	if err := os.WriteFile(certFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err == nil {
+		t.Fatal("got no error reloading a broken certificate")
+	}
+	if got := serverSerial(); got.Cmp(second.cert.SerialNumber) != 0 {
+		t.Fatalf("got serial %s after failed reload, want %s", got, second.cert.SerialNumber)
+	}
+}
+
+func TestServer_Watch(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newCert(t, "player.test", ca).write(t, dir, "server")
+
+	srv, err := NewServer(testServerConfig{certFile: certFile, keyFile: keyFile}, newLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.watch(ctx, 10*time.Millisecond)
+
+	second := newCert(t, "player.test", ca)
+	second.write(t, dir, "server")
+
+	cfg, err := Client(caFile, "", "", "player.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for i := 1; ; i++ {
+		// the watcher may have taken the initial state after the write,
+		// and the modification time may have a coarse resolution,
+		// so the time is moved explicitly until the change is noticed
+		later := time.Now().Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(certFile, later, later); err != nil {
+			t.Fatal(err)
+		}
+		state, _, err := handshake(t, srv, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.PeerCertificates[0].SerialNumber.Cmp(second.cert.SerialNumber) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("certificate is not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
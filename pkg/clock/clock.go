@@ -0,0 +1,47 @@
+// Package clock abstracts the source of time of the player,
+// so that tests can replace it with a clock they move themselves.
+package clock
+
+import "time"
+
+// Clock is a source of time.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a timer firing after d, at once if d is not positive.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer sends the time on its channel once it fires.
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, it reports whether the timer was stopped
+	// before it fired.
+	Stop() bool
+}
+
+// New returns the system clock.
+func New() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{t: time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
@@ -0,0 +1,5 @@
+package grpcapi
+
+// ErrorDomain is set as google.rpc.ErrorInfo.domain of errors returned by PlayerService
+// along with ErrorReason names as reasons.
+const ErrorDomain = "player.gocloudcamp"
@@ -0,0 +1,8 @@
+package grpcapi
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI v2 document of the REST gateway, generated from service.proto.
+//
+//go:embed service.swagger.json
+var OpenAPI []byte
@@ -0,0 +1,914 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: service.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PlayerService_Play_FullMethodName             = "/grpcapi.PlayerService/Play"
+	PlayerService_Pause_FullMethodName            = "/grpcapi.PlayerService/Pause"
+	PlayerService_Next_FullMethodName             = "/grpcapi.PlayerService/Next"
+	PlayerService_Prev_FullMethodName             = "/grpcapi.PlayerService/Prev"
+	PlayerService_Seek_FullMethodName             = "/grpcapi.PlayerService/Seek"
+	PlayerService_GetStatus_FullMethodName        = "/grpcapi.PlayerService/GetStatus"
+	PlayerService_SetSleepTimer_FullMethodName    = "/grpcapi.PlayerService/SetSleepTimer"
+	PlayerService_GetSleepTimer_FullMethodName    = "/grpcapi.PlayerService/GetSleepTimer"
+	PlayerService_CancelSleepTimer_FullMethodName = "/grpcapi.PlayerService/CancelSleepTimer"
+	PlayerService_CreateAudio_FullMethodName      = "/grpcapi.PlayerService/CreateAudio"
+	PlayerService_ReadAudio_FullMethodName        = "/grpcapi.PlayerService/ReadAudio"
+	PlayerService_UpdateAudio_FullMethodName      = "/grpcapi.PlayerService/UpdateAudio"
+	PlayerService_DeleteAudio_FullMethodName      = "/grpcapi.PlayerService/DeleteAudio"
+	PlayerService_ListAudio_FullMethodName        = "/grpcapi.PlayerService/ListAudio"
+	PlayerService_MoveAudio_FullMethodName        = "/grpcapi.PlayerService/MoveAudio"
+	PlayerService_ListTrash_FullMethodName        = "/grpcapi.PlayerService/ListTrash"
+	PlayerService_RestoreAudio_FullMethodName     = "/grpcapi.PlayerService/RestoreAudio"
+	PlayerService_PurgeTrash_FullMethodName       = "/grpcapi.PlayerService/PurgeTrash"
+	PlayerService_Undo_FullMethodName             = "/grpcapi.PlayerService/Undo"
+	PlayerService_Redo_FullMethodName             = "/grpcapi.PlayerService/Redo"
+	PlayerService_ListAuditEvents_FullMethodName  = "/grpcapi.PlayerService/ListAuditEvents"
+	PlayerService_WatchEvents_FullMethodName      = "/grpcapi.PlayerService/WatchEvents"
+)
+
+// PlayerServiceClient is the client API for PlayerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PlayerServiceClient interface {
+	Play(ctx context.Context, in *PlayRequest, opts ...grpc.CallOption) (*PlayResponse, error)
+	Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseResponse, error)
+	Next(ctx context.Context, in *NextRequest, opts ...grpc.CallOption) (*NextResponse, error)
+	Prev(ctx context.Context, in *PrevRequest, opts ...grpc.CallOption) (*PrevResponse, error)
+	Seek(ctx context.Context, in *SeekRequest, opts ...grpc.CallOption) (*SeekResponse, error)
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	SetSleepTimer(ctx context.Context, in *SetSleepTimerRequest, opts ...grpc.CallOption) (*SetSleepTimerResponse, error)
+	GetSleepTimer(ctx context.Context, in *GetSleepTimerRequest, opts ...grpc.CallOption) (*GetSleepTimerResponse, error)
+	CancelSleepTimer(ctx context.Context, in *CancelSleepTimerRequest, opts ...grpc.CallOption) (*CancelSleepTimerResponse, error)
+	CreateAudio(ctx context.Context, in *CreateAudioRequest, opts ...grpc.CallOption) (*CreateAudioResponse, error)
+	ReadAudio(ctx context.Context, in *ReadAudioRequest, opts ...grpc.CallOption) (*ReadAudioResponse, error)
+	UpdateAudio(ctx context.Context, in *UpdateAudioRequest, opts ...grpc.CallOption) (*UpdateAudioResponse, error)
+	DeleteAudio(ctx context.Context, in *DeleteAudioRequest, opts ...grpc.CallOption) (*DeleteAudioResponse, error)
+	ListAudio(ctx context.Context, in *ListAudioRequest, opts ...grpc.CallOption) (*ListAudioResponse, error)
+	MoveAudio(ctx context.Context, in *MoveAudioRequest, opts ...grpc.CallOption) (*MoveAudioResponse, error)
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error)
+	RestoreAudio(ctx context.Context, in *RestoreAudioRequest, opts ...grpc.CallOption) (*RestoreAudioResponse, error)
+	PurgeTrash(ctx context.Context, in *PurgeTrashRequest, opts ...grpc.CallOption) (*PurgeTrashResponse, error)
+	Undo(ctx context.Context, in *UndoRequest, opts ...grpc.CallOption) (*UndoResponse, error)
+	Redo(ctx context.Context, in *RedoRequest, opts ...grpc.CallOption) (*RedoResponse, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (PlayerService_WatchEventsClient, error)
+}
+
+type playerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPlayerServiceClient(cc grpc.ClientConnInterface) PlayerServiceClient {
+	return &playerServiceClient{cc}
+}
+
+func (c *playerServiceClient) Play(ctx context.Context, in *PlayRequest, opts ...grpc.CallOption) (*PlayResponse, error) {
+	out := new(PlayResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Play_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseResponse, error) {
+	out := new(PauseResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Pause_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) Next(ctx context.Context, in *NextRequest, opts ...grpc.CallOption) (*NextResponse, error) {
+	out := new(NextResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Next_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) Prev(ctx context.Context, in *PrevRequest, opts ...grpc.CallOption) (*PrevResponse, error) {
+	out := new(PrevResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Prev_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) Seek(ctx context.Context, in *SeekRequest, opts ...grpc.CallOption) (*SeekResponse, error) {
+	out := new(SeekResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Seek_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, PlayerService_GetStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) SetSleepTimer(ctx context.Context, in *SetSleepTimerRequest, opts ...grpc.CallOption) (*SetSleepTimerResponse, error) {
+	out := new(SetSleepTimerResponse)
+	err := c.cc.Invoke(ctx, PlayerService_SetSleepTimer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) GetSleepTimer(ctx context.Context, in *GetSleepTimerRequest, opts ...grpc.CallOption) (*GetSleepTimerResponse, error) {
+	out := new(GetSleepTimerResponse)
+	err := c.cc.Invoke(ctx, PlayerService_GetSleepTimer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) CancelSleepTimer(ctx context.Context, in *CancelSleepTimerRequest, opts ...grpc.CallOption) (*CancelSleepTimerResponse, error) {
+	out := new(CancelSleepTimerResponse)
+	err := c.cc.Invoke(ctx, PlayerService_CancelSleepTimer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) CreateAudio(ctx context.Context, in *CreateAudioRequest, opts ...grpc.CallOption) (*CreateAudioResponse, error) {
+	out := new(CreateAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_CreateAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) ReadAudio(ctx context.Context, in *ReadAudioRequest, opts ...grpc.CallOption) (*ReadAudioResponse, error) {
+	out := new(ReadAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_ReadAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) UpdateAudio(ctx context.Context, in *UpdateAudioRequest, opts ...grpc.CallOption) (*UpdateAudioResponse, error) {
+	out := new(UpdateAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_UpdateAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) DeleteAudio(ctx context.Context, in *DeleteAudioRequest, opts ...grpc.CallOption) (*DeleteAudioResponse, error) {
+	out := new(DeleteAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_DeleteAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) ListAudio(ctx context.Context, in *ListAudioRequest, opts ...grpc.CallOption) (*ListAudioResponse, error) {
+	out := new(ListAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_ListAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) MoveAudio(ctx context.Context, in *MoveAudioRequest, opts ...grpc.CallOption) (*MoveAudioResponse, error) {
+	out := new(MoveAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_MoveAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error) {
+	out := new(ListTrashResponse)
+	err := c.cc.Invoke(ctx, PlayerService_ListTrash_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) RestoreAudio(ctx context.Context, in *RestoreAudioRequest, opts ...grpc.CallOption) (*RestoreAudioResponse, error) {
+	out := new(RestoreAudioResponse)
+	err := c.cc.Invoke(ctx, PlayerService_RestoreAudio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) PurgeTrash(ctx context.Context, in *PurgeTrashRequest, opts ...grpc.CallOption) (*PurgeTrashResponse, error) {
+	out := new(PurgeTrashResponse)
+	err := c.cc.Invoke(ctx, PlayerService_PurgeTrash_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) Undo(ctx context.Context, in *UndoRequest, opts ...grpc.CallOption) (*UndoResponse, error) {
+	out := new(UndoResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Undo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) Redo(ctx context.Context, in *RedoRequest, opts ...grpc.CallOption) (*RedoResponse, error) {
+	out := new(RedoResponse)
+	err := c.cc.Invoke(ctx, PlayerService_Redo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, PlayerService_ListAuditEvents_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *playerServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (PlayerService_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &PlayerService_ServiceDesc.Streams[0], PlayerService_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &playerServiceWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PlayerService_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type playerServiceWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *playerServiceWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PlayerServiceServer is the server API for PlayerService service.
+// All implementations must embed UnimplementedPlayerServiceServer
+// for forward compatibility
+type PlayerServiceServer interface {
+	Play(context.Context, *PlayRequest) (*PlayResponse, error)
+	Pause(context.Context, *PauseRequest) (*PauseResponse, error)
+	Next(context.Context, *NextRequest) (*NextResponse, error)
+	Prev(context.Context, *PrevRequest) (*PrevResponse, error)
+	Seek(context.Context, *SeekRequest) (*SeekResponse, error)
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	SetSleepTimer(context.Context, *SetSleepTimerRequest) (*SetSleepTimerResponse, error)
+	GetSleepTimer(context.Context, *GetSleepTimerRequest) (*GetSleepTimerResponse, error)
+	CancelSleepTimer(context.Context, *CancelSleepTimerRequest) (*CancelSleepTimerResponse, error)
+	CreateAudio(context.Context, *CreateAudioRequest) (*CreateAudioResponse, error)
+	ReadAudio(context.Context, *ReadAudioRequest) (*ReadAudioResponse, error)
+	UpdateAudio(context.Context, *UpdateAudioRequest) (*UpdateAudioResponse, error)
+	DeleteAudio(context.Context, *DeleteAudioRequest) (*DeleteAudioResponse, error)
+	ListAudio(context.Context, *ListAudioRequest) (*ListAudioResponse, error)
+	MoveAudio(context.Context, *MoveAudioRequest) (*MoveAudioResponse, error)
+	ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error)
+	RestoreAudio(context.Context, *RestoreAudioRequest) (*RestoreAudioResponse, error)
+	PurgeTrash(context.Context, *PurgeTrashRequest) (*PurgeTrashResponse, error)
+	Undo(context.Context, *UndoRequest) (*UndoResponse, error)
+	Redo(context.Context, *RedoRequest) (*RedoResponse, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	WatchEvents(*WatchEventsRequest, PlayerService_WatchEventsServer) error
+	mustEmbedUnimplementedPlayerServiceServer()
+}
+
+// UnimplementedPlayerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPlayerServiceServer struct {
+}
+
+func (UnimplementedPlayerServiceServer) Play(context.Context, *PlayRequest) (*PlayResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Play not implemented")
+}
+func (UnimplementedPlayerServiceServer) Pause(context.Context, *PauseRequest) (*PauseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedPlayerServiceServer) Next(context.Context, *NextRequest) (*NextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Next not implemented")
+}
+func (UnimplementedPlayerServiceServer) Prev(context.Context, *PrevRequest) (*PrevResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Prev not implemented")
+}
+func (UnimplementedPlayerServiceServer) Seek(context.Context, *SeekRequest) (*SeekResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Seek not implemented")
+}
+func (UnimplementedPlayerServiceServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedPlayerServiceServer) SetSleepTimer(context.Context, *SetSleepTimerRequest) (*SetSleepTimerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSleepTimer not implemented")
+}
+func (UnimplementedPlayerServiceServer) GetSleepTimer(context.Context, *GetSleepTimerRequest) (*GetSleepTimerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSleepTimer not implemented")
+}
+func (UnimplementedPlayerServiceServer) CancelSleepTimer(context.Context, *CancelSleepTimerRequest) (*CancelSleepTimerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelSleepTimer not implemented")
+}
+func (UnimplementedPlayerServiceServer) CreateAudio(context.Context, *CreateAudioRequest) (*CreateAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) ReadAudio(context.Context, *ReadAudioRequest) (*ReadAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) UpdateAudio(context.Context, *UpdateAudioRequest) (*UpdateAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) DeleteAudio(context.Context, *DeleteAudioRequest) (*DeleteAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) ListAudio(context.Context, *ListAudioRequest) (*ListAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) MoveAudio(context.Context, *MoveAudioRequest) (*MoveAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedPlayerServiceServer) RestoreAudio(context.Context, *RestoreAudioRequest) (*RestoreAudioResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreAudio not implemented")
+}
+func (UnimplementedPlayerServiceServer) PurgeTrash(context.Context, *PurgeTrashRequest) (*PurgeTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeTrash not implemented")
+}
+func (UnimplementedPlayerServiceServer) Undo(context.Context, *UndoRequest) (*UndoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Undo not implemented")
+}
+func (UnimplementedPlayerServiceServer) Redo(context.Context, *RedoRequest) (*RedoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Redo not implemented")
+}
+func (UnimplementedPlayerServiceServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedPlayerServiceServer) WatchEvents(*WatchEventsRequest, PlayerService_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedPlayerServiceServer) mustEmbedUnimplementedPlayerServiceServer() {}
+
+// UnsafePlayerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PlayerServiceServer will
+// result in compilation errors.
+type UnsafePlayerServiceServer interface {
+	mustEmbedUnimplementedPlayerServiceServer()
+}
+
+func RegisterPlayerServiceServer(s grpc.ServiceRegistrar, srv PlayerServiceServer) {
+	s.RegisterService(&PlayerService_ServiceDesc, srv)
+}
+
+func _PlayerService_Play_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlayRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Play(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Play_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Play(ctx, req.(*PlayRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Pause_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Pause(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_Next_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Next(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Next_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Next(ctx, req.(*NextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_Prev_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrevRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Prev(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Prev_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Prev(ctx, req.(*PrevRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_Seek_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SeekRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Seek(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Seek_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Seek(ctx, req.(*SeekRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_SetSleepTimer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSleepTimerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).SetSleepTimer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_SetSleepTimer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).SetSleepTimer(ctx, req.(*SetSleepTimerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_GetSleepTimer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSleepTimerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).GetSleepTimer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_GetSleepTimer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).GetSleepTimer(ctx, req.(*GetSleepTimerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_CancelSleepTimer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelSleepTimerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).CancelSleepTimer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_CancelSleepTimer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).CancelSleepTimer(ctx, req.(*CancelSleepTimerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_CreateAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).CreateAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_CreateAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).CreateAudio(ctx, req.(*CreateAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_ReadAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).ReadAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_ReadAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).ReadAudio(ctx, req.(*ReadAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_UpdateAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).UpdateAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_UpdateAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).UpdateAudio(ctx, req.(*UpdateAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_DeleteAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).DeleteAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_DeleteAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).DeleteAudio(ctx, req.(*DeleteAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_ListAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).ListAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_ListAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).ListAudio(ctx, req.(*ListAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_MoveAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).MoveAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_MoveAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).MoveAudio(ctx, req.(*MoveAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_RestoreAudio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreAudioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).RestoreAudio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_RestoreAudio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).RestoreAudio(ctx, req.(*RestoreAudioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_PurgeTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).PurgeTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_PurgeTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).PurgeTrash(ctx, req.(*PurgeTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_Undo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Undo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Undo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Undo(ctx, req.(*UndoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_Redo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).Redo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_Redo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).Redo(ctx, req.(*RedoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PlayerServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PlayerService_ListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PlayerServiceServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PlayerService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PlayerServiceServer).WatchEvents(m, &playerServiceWatchEventsServer{stream})
+}
+
+type PlayerService_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type playerServiceWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *playerServiceWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// PlayerService_ServiceDesc is the grpc.ServiceDesc for PlayerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PlayerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpcapi.PlayerService",
+	HandlerType: (*PlayerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Play",
+			Handler:    _PlayerService_Play_Handler,
+		},
+		{
+			MethodName: "Pause",
+			Handler:    _PlayerService_Pause_Handler,
+		},
+		{
+			MethodName: "Next",
+			Handler:    _PlayerService_Next_Handler,
+		},
+		{
+			MethodName: "Prev",
+			Handler:    _PlayerService_Prev_Handler,
+		},
+		{
+			MethodName: "Seek",
+			Handler:    _PlayerService_Seek_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _PlayerService_GetStatus_Handler,
+		},
+		{
+			MethodName: "SetSleepTimer",
+			Handler:    _PlayerService_SetSleepTimer_Handler,
+		},
+		{
+			MethodName: "GetSleepTimer",
+			Handler:    _PlayerService_GetSleepTimer_Handler,
+		},
+		{
+			MethodName: "CancelSleepTimer",
+			Handler:    _PlayerService_CancelSleepTimer_Handler,
+		},
+		{
+			MethodName: "CreateAudio",
+			Handler:    _PlayerService_CreateAudio_Handler,
+		},
+		{
+			MethodName: "ReadAudio",
+			Handler:    _PlayerService_ReadAudio_Handler,
+		},
+		{
+			MethodName: "UpdateAudio",
+			Handler:    _PlayerService_UpdateAudio_Handler,
+		},
+		{
+			MethodName: "DeleteAudio",
+			Handler:    _PlayerService_DeleteAudio_Handler,
+		},
+		{
+			MethodName: "ListAudio",
+			Handler:    _PlayerService_ListAudio_Handler,
+		},
+		{
+			MethodName: "MoveAudio",
+			Handler:    _PlayerService_MoveAudio_Handler,
+		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _PlayerService_ListTrash_Handler,
+		},
+		{
+			MethodName: "RestoreAudio",
+			Handler:    _PlayerService_RestoreAudio_Handler,
+		},
+		{
+			MethodName: "PurgeTrash",
+			Handler:    _PlayerService_PurgeTrash_Handler,
+		},
+		{
+			MethodName: "Undo",
+			Handler:    _PlayerService_Undo_Handler,
+		},
+		{
+			MethodName: "Redo",
+			Handler:    _PlayerService_Redo_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _PlayerService_ListAuditEvents_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _PlayerService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "service.proto",
+}
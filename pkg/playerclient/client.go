@@ -0,0 +1,491 @@
+// Package playerclient is a client of the player service.
+//
+//	c, err := playerclient.New("player.example.com:50052",
+//		playerclient.WithTLS(&tls.Config{}), playerclient.WithAPIKey(key))
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	if err := c.Play(ctx); errors.Is(err, playerclient.ErrNoAudio) {
+//		...
+//	}
+//
+// Calls that can be repeated safely are retried after transient errors
+// according to the retry policy, see RetryPolicy.
+package playerclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Audio is an audio of the playlist.
+type Audio struct {
+	Id       string
+	Name     string
+	Duration time.Duration
+	// Version is increased on every change of the audio.
+	Version uint64
+}
+
+// DeletedAudio is an audio kept in the trash after deletion.
+type DeletedAudio struct {
+	Audio
+	// Index is the position the audio had in the playlist.
+	Index     int
+	DeletedAt time.Time
+	// ExpiresAt is the time the audio is removed from the trash permanently.
+	ExpiresAt time.Time
+}
+
+// Change is a change of the playlist applied by Undo or Redo.
+type Change struct {
+	// Operation is the applied operation: delete, restore, update or move.
+	Operation string
+	// Audio is the deleted, restored, updated or moved audio.
+	Audio *Audio
+	// Index is the position of the restored or moved audio.
+	Index int
+}
+
+// SleepTimer pauses playback at the time or after the number of tracks.
+type SleepTimer struct {
+	// At, if not zero, is the time playback is paused at.
+	At time.Time
+	// Tracks, if not zero, is the number of tracks left to be played to the end
+	// before playback is paused, the following audio is loaded but not played.
+	Tracks int
+}
+
+// State is a state of the player.
+type State string
+
+const (
+	NoActiveAudio State = "no_active_audio"
+	Playing       State = "playing"
+	Paused        State = "paused"
+	Closed        State = "closed"
+)
+
+// Status is a snapshot of the player state.
+type Status struct {
+	State State
+	// Audio is the current audio, nil if there is no active audio.
+	Audio *Audio
+	// Position is the elapsed playback time of the current audio.
+	Position time.Duration
+}
+
+// Client calls the player service. It is safe for concurrent use.
+type Client struct {
+	api      grpcapi.PlayerServiceClient
+	conn     *grpc.ClientConn
+	callOpts []grpc.CallOption
+	retry    RetryPolicy
+}
+
+// New connects to the server at addr.
+func New(addr string, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	transportCreds := insecure.NewCredentials()
+	if o.tls != nil {
+		transportCreds = credentials.NewTLS(o.tls)
+	}
+	dialOpts := append([]grpc.DialOption{grpc.WithTransportCredentials(transportCreds)}, o.dialOptions...)
+	conn, err := grpc.Dial(addr, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial error: %w", err)
+	}
+	c := newClient(conn, o)
+	c.conn = conn
+	return c, nil
+}
+
+// NewFromConn creates a client using the connection, e.g. one with custom
+// dialer. The connection is not closed by Close.
+func NewFromConn(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	return newClient(conn, newOptions(opts))
+}
+
+func newOptions(opts []Option) options {
+	o := options{retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func newClient(conn grpc.ClientConnInterface, o options) *Client {
+	c := &Client{
+		api:   grpcapi.NewPlayerServiceClient(conn),
+		retry: o.retry,
+	}
+	if o.apiKey != "" || o.token != "" {
+		c.callOpts = append(c.callOpts, grpc.PerRPCCredentials(NewCallCredentials(o.apiKey, o.token, o.tls != nil)))
+	}
+	return c
+}
+
+// Close closes the connection opened by New.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// Play starts or resumes playing, it is retried.
+func (c *Client) Play(ctx context.Context) error {
+	return c.withRetry(ctx, func(ctx context.Context) error {
+		_, err := c.api.Play(ctx, &grpcapi.PlayRequest{}, c.callOpts...)
+		return err
+	})
+}
+
+// Pause pauses playing, it is retried.
+func (c *Client) Pause(ctx context.Context) error {
+	return c.withRetry(ctx, func(ctx context.Context) error {
+		_, err := c.api.Pause(ctx, &grpcapi.PauseRequest{}, c.callOpts...)
+		return err
+	})
+}
+
+// Next plays the next audio. It is not retried,
+// as a repeated call would skip one more audio.
+func (c *Client) Next(ctx context.Context) error {
+	_, err := c.api.Next(ctx, &grpcapi.NextRequest{}, c.callOpts...)
+	return fromStatus(err)
+}
+
+// Prev plays the previous audio. It is not retried,
+// as a repeated call would skip one more audio.
+func (c *Client) Prev(ctx context.Context) error {
+	_, err := c.api.Prev(ctx, &grpcapi.PrevRequest{}, c.callOpts...)
+	return fromStatus(err)
+}
+
+// Seek continues the current audio from the position, it is retried.
+func (c *Client) Seek(ctx context.Context, position time.Duration) error {
+	return c.withRetry(ctx, func(ctx context.Context) error {
+		_, err := c.api.Seek(ctx, &grpcapi.SeekRequest{Position: durationpb.New(position)}, c.callOpts...)
+		return err
+	})
+}
+
+// Status returns the player state, it is retried.
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	var st Status
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		resp, err := c.api.GetStatus(ctx, &grpcapi.GetStatusRequest{}, c.callOpts...)
+		if err != nil {
+			return err
+		}
+		st = Status{
+			State:    State(resp.GetState()),
+			Audio:    fromAudio(resp.GetAudio()),
+			Position: resp.GetPosition().AsDuration(),
+		}
+		return nil
+	})
+	return st, err
+}
+
+// SetSleepTimerAfter replaces the sleep timer with the one pausing playback after d.
+// It is retried, a retry counts d from the time it is made.
+func (c *Client) SetSleepTimerAfter(ctx context.Context, d time.Duration) (*SleepTimer, error) {
+	return c.setSleepTimer(ctx, &grpcapi.SetSleepTimerRequest{
+		Timer: &grpcapi.SetSleepTimerRequest_Duration{Duration: durationpb.New(d)},
+	})
+}
+
+// SetSleepTimerAt replaces the sleep timer with the one pausing playback at t.
+// ErrSleepTimerInPast is returned if t has passed. It is retried.
+func (c *Client) SetSleepTimerAt(ctx context.Context, t time.Time) (*SleepTimer, error) {
+	return c.setSleepTimer(ctx, &grpcapi.SetSleepTimerRequest{
+		Timer: &grpcapi.SetSleepTimerRequest_Time{Time: timestamppb.New(t)},
+	})
+}
+
+// SetSleepTimerTracks replaces the sleep timer with the one pausing playback
+// after n tracks are played to the end, 1 means the current one. It is retried.
+func (c *Client) SetSleepTimerTracks(ctx context.Context, n int) (*SleepTimer, error) {
+	return c.setSleepTimer(ctx, &grpcapi.SetSleepTimerRequest{
+		Timer: &grpcapi.SetSleepTimerRequest_Tracks{Tracks: int32(n)},
+	})
+}
+
+func (c *Client) setSleepTimer(ctx context.Context, req *grpcapi.SetSleepTimerRequest) (*SleepTimer, error) {
+	var t *SleepTimer
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		resp, err := c.api.SetSleepTimer(ctx, req, c.callOpts...)
+		if err != nil {
+			return err
+		}
+		t = fromSleepTimer(resp.GetSleepTimer())
+		return nil
+	})
+	return t, err
+}
+
+// SleepTimer returns the sleep timer, nil if there is none. It is retried.
+func (c *Client) SleepTimer(ctx context.Context) (*SleepTimer, error) {
+	var t *SleepTimer
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		resp, err := c.api.GetSleepTimer(ctx, &grpcapi.GetSleepTimerRequest{}, c.callOpts...)
+		if err != nil {
+			return err
+		}
+		t = fromSleepTimer(resp.GetSleepTimer())
+		return nil
+	})
+	return t, err
+}
+
+// CancelSleepTimer removes the sleep timer if there is one, it is retried.
+func (c *Client) CancelSleepTimer(ctx context.Context) error {
+	return c.withRetry(ctx, func(ctx context.Context) error {
+		_, err := c.api.CancelSleepTimer(ctx, &grpcapi.CancelSleepTimerRequest{}, c.callOpts...)
+		return err
+	})
+}
+
+// CreateAudio adds the audio to the end of the playlist, the id is assigned
+// by the server. It is not retried, as a repeated call would add one more audio.
+func (c *Client) CreateAudio(ctx context.Context, name string, duration time.Duration) (*Audio, error) {
+	resp, err := c.api.CreateAudio(ctx, &grpcapi.CreateAudioRequest{
+		Audio: &grpcapi.Audio{Name: name, Duration: durationpb.New(duration)},
+	}, c.callOpts...)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromAudio(resp.GetAudio()), nil
+}
+
+// GetAudio returns the audio, it is retried.
+func (c *Client) GetAudio(ctx context.Context, id string) (*Audio, error) {
+	var a *Audio
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		resp, err := c.api.ReadAudio(ctx, &grpcapi.ReadAudioRequest{Id: id}, c.callOpts...)
+		if err != nil {
+			return err
+		}
+		a = fromAudio(resp.GetAudio())
+		return nil
+	})
+	return a, err
+}
+
+// UpdateAudio replaces the name and the duration of the audio with a.Id.
+// If expectedVersion is not zero, the audio must have it, otherwise
+// ErrVersionMismatch is returned. It is not retried.
+func (c *Client) UpdateAudio(ctx context.Context, a Audio, expectedVersion uint64) (*Audio, error) {
+	resp, err := c.api.UpdateAudio(ctx, &grpcapi.UpdateAudioRequest{
+		Audio: &grpcapi.Audio{
+			Id:       a.Id,
+			Name:     a.Name,
+			Duration: durationpb.New(a.Duration),
+		},
+		ExpectedVersion: expectedVersion,
+	}, c.callOpts...)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromAudio(resp.GetAudio()), nil
+}
+
+// DeleteAudio removes the audio to the trash. If expectedVersion is not zero, the audio
+// must have it, otherwise ErrVersionMismatch is returned. It is not retried.
+func (c *Client) DeleteAudio(ctx context.Context, id string, expectedVersion uint64) error {
+	_, err := c.api.DeleteAudio(ctx, &grpcapi.DeleteAudioRequest{Id: id, ExpectedVersion: expectedVersion}, c.callOpts...)
+	return fromStatus(err)
+}
+
+// ListAudio returns the playlist and its revision, it is retried.
+func (c *Client) ListAudio(ctx context.Context) ([]Audio, uint64, error) {
+	var (
+		audios   []Audio
+		revision uint64
+	)
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		resp, err := c.api.ListAudio(ctx, &grpcapi.ListAudioRequest{}, c.callOpts...)
+		if err != nil {
+			return err
+		}
+		audios = make([]Audio, 0, len(resp.GetAudio()))
+		for _, a := range resp.GetAudio() {
+			audios = append(audios, *fromAudio(a))
+		}
+		revision = resp.GetRevision()
+		return nil
+	})
+	return audios, revision, err
+}
+
+// MoveAudio moves the audio to the index in the playlist, an index past
+// the end moves it to the end. It is retried.
+func (c *Client) MoveAudio(ctx context.Context, id string, index int) error {
+	return c.withRetry(ctx, func(ctx context.Context) error {
+		_, err := c.api.MoveAudio(ctx, &grpcapi.MoveAudioRequest{Id: id, Index: int32(index)}, c.callOpts...)
+		return err
+	})
+}
+
+// ListTrash returns deleted audios kept in the trash, the latest deleted first,
+// it is retried.
+func (c *Client) ListTrash(ctx context.Context) ([]DeletedAudio, error) {
+	var audios []DeletedAudio
+	err := c.withRetry(ctx, func(ctx context.Context) error {
+		resp, err := c.api.ListTrash(ctx, &grpcapi.ListTrashRequest{}, c.callOpts...)
+		if err != nil {
+			return err
+		}
+		audios = make([]DeletedAudio, 0, len(resp.GetAudios()))
+		for _, a := range resp.GetAudios() {
+			audios = append(audios, DeletedAudio{
+				Audio:     *fromAudio(a.GetAudio()),
+				Index:     int(a.GetIndex()),
+				DeletedAt: a.GetDeleteTime().AsTime(),
+				ExpiresAt: a.GetExpireTime().AsTime(),
+			})
+		}
+		return nil
+	})
+	return audios, err
+}
+
+// RestoreAudio moves the audio from the trash back to the playlist and returns it
+// with the index it is restored to. ErrNotInTrash is returned if the trash
+// does not keep the audio. It is not retried.
+func (c *Client) RestoreAudio(ctx context.Context, id string) (*Audio, int, error) {
+	resp, err := c.api.RestoreAudio(ctx, &grpcapi.RestoreAudioRequest{Id: id}, c.callOpts...)
+	if err != nil {
+		return nil, 0, fromStatus(err)
+	}
+	return fromAudio(resp.GetAudio()), int(resp.GetIndex()), nil
+}
+
+// PurgeTrash removes audios with the ids from the trash permanently, all of them
+// if no id is given, and returns the number of removed audios. It is not retried.
+func (c *Client) PurgeTrash(ctx context.Context, ids ...string) (int, error) {
+	resp, err := c.api.PurgeTrash(ctx, &grpcapi.PurgeTrashRequest{Ids: ids}, c.callOpts...)
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return int(resp.GetPurged()), nil
+}
+
+// Undo undoes the latest change of the playlist made by the caller or,
+// if global is true, by anyone. ErrNothingToUndo is returned if there is none.
+// It is not retried.
+func (c *Client) Undo(ctx context.Context, global bool) (*Change, error) {
+	resp, err := c.api.Undo(ctx, &grpcapi.UndoRequest{Global: global}, c.callOpts...)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromChange(resp.GetChange()), nil
+}
+
+// Redo redoes the latest undone change of the playlist made by the caller or,
+// if global is true, by anyone. ErrNothingToRedo is returned if there is none.
+// It is not retried.
+func (c *Client) Redo(ctx context.Context, global bool) (*Change, error) {
+	resp, err := c.api.Redo(ctx, &grpcapi.RedoRequest{Global: global}, c.callOpts...)
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return fromChange(resp.GetChange()), nil
+}
+
+// withRetry calls fn until it succeeds, returns a non-transient error
+// or the attempts of the retry policy are exhausted.
+func (c *Client) withRetry(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := c.retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		delay, ok := retryDelay(err)
+		if !ok || attempt >= c.retry.MaxAttempts {
+			return fromStatus(err)
+		}
+		if delay == 0 {
+			// full jitter in the upper half spreads retries of concurrent clients
+			delay = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+			backoff = min(backoff*2, c.retry.MaxBackoff)
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fromStatus(err)
+		case <-t.C:
+		}
+	}
+}
+
+// retryDelay reports whether the error is transient and the delay
+// requested by the server, zero if the backoff should be used.
+func retryDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	switch st.Code() {
+	case codes.Unavailable:
+		return 0, true
+	case codes.ResourceExhausted:
+		// only calls rejected by rate limits or the full player queue tell when to retry
+		for _, d := range st.Details() {
+			if ri, ok := d.(*errdetails.RetryInfo); ok {
+				return ri.GetRetryDelay().AsDuration(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func fromAudio(a *grpcapi.Audio) *Audio {
+	if a == nil {
+		return nil
+	}
+	return &Audio{
+		Id:       a.GetId(),
+		Name:     a.GetName(),
+		Duration: a.GetDuration().AsDuration(),
+		Version:  a.GetVersion(),
+	}
+}
+
+func fromChange(c *grpcapi.Change) *Change {
+	return &Change{
+		Operation: c.GetOperation(),
+		Audio:     fromAudio(c.GetAudio()),
+		Index:     int(c.GetIndex()),
+	}
+}
+
+func fromSleepTimer(t *grpcapi.SleepTimer) *SleepTimer {
+	if t == nil {
+		return nil
+	}
+	res := &SleepTimer{Tracks: int(t.GetTracks())}
+	if t.GetFireTime() != nil {
+		res.At = t.GetFireTime().AsTime()
+	}
+	return res
+}
@@ -0,0 +1,164 @@
+package playerclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// failingServer fails GetStatus with the errors in turn, then succeeds.
+type failingServer struct {
+	grpcapi.UnimplementedPlayerServiceServer
+
+	mtx   sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (s *failingServer) GetStatus(context.Context, *grpcapi.GetStatusRequest) (*grpcapi.GetStatusResponse, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.calls++
+	if len(s.errs) == 0 {
+		return &grpcapi.GetStatusResponse{State: string(Playing)}, nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return nil, err
+}
+
+// newTestClient returns a client connected to srv over an in-memory listener.
+func newTestClient(t *testing.T, srv grpcapi.PlayerServiceServer, opts ...Option) *Client {
+	t.Helper()
+	s := grpc.NewServer()
+	grpcapi.RegisterPlayerServiceServer(s, srv)
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return NewFromConn(conn, opts...)
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      time.Duration
+		wantRetry bool
+	}{
+		{name: "unavailable", err: status.Error(codes.Unavailable, "down"), wantRetry: true},
+		{
+			name:      "resource exhausted with retry info",
+			err:       statusError(t, codes.ResourceExhausted, &errdetails.RetryInfo{RetryDelay: durationpb.New(2 * time.Second)}),
+			want:      2 * time.Second,
+			wantRetry: true,
+		},
+		{name: "resource exhausted without retry info", err: status.Error(codes.ResourceExhausted, "slow subscriber")},
+		{name: "not found", err: status.Error(codes.NotFound, "not found")},
+		{name: "internal", err: status.Error(codes.Internal, "failed")},
+		{name: "not a status", err: errors.New("plain")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, retry := retryDelay(tt.err)
+			if got != tt.want || retry != tt.wantRetry {
+				t.Errorf("retryDelay() = %s, %v, want %s, %v", got, retry, tt.want, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestClient_WithRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+	tests := []struct {
+		name        string
+		maxAttempts int
+		errs        []error
+		wantCode    codes.Code
+		wantCalls   int
+	}{
+		{name: "success", maxAttempts: 3, wantCode: codes.OK, wantCalls: 1},
+		{name: "transient then success", maxAttempts: 3, errs: []error{unavailable, unavailable}, wantCode: codes.OK, wantCalls: 3},
+		{
+			name:        "retry info",
+			maxAttempts: 3,
+			errs:        []error{statusError(t, codes.ResourceExhausted, &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Millisecond)})},
+			wantCode:    codes.OK,
+			wantCalls:   2,
+		},
+		{name: "non-transient", maxAttempts: 3, errs: []error{status.Error(codes.NotFound, "not found")}, wantCode: codes.NotFound, wantCalls: 1},
+		{
+			name:        "resource exhausted without retry info",
+			maxAttempts: 3,
+			errs:        []error{status.Error(codes.ResourceExhausted, "exhausted")},
+			wantCode:    codes.ResourceExhausted,
+			wantCalls:   1,
+		},
+		{name: "attempts exhausted", maxAttempts: 3, errs: []error{unavailable, unavailable, unavailable, unavailable}, wantCode: codes.Unavailable, wantCalls: 3},
+		{name: "retries disabled", maxAttempts: 1, errs: []error{unavailable}, wantCode: codes.Unavailable, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &failingServer{errs: tt.errs}
+			c := newTestClient(t, srv, WithRetryPolicy(RetryPolicy{
+				MaxAttempts:    tt.maxAttempts,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+			}))
+
+			st, err := c.Status(context.Background())
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("Status() error = %v, want code %s", err, tt.wantCode)
+			}
+			if err == nil && st.State != Playing {
+				t.Errorf("Status() state = %s, want %s", st.State, Playing)
+			}
+			var e *Error
+			if err != nil && !errors.As(err, &e) {
+				t.Errorf("Status() error = %#v, want *Error", err)
+			}
+			if srv.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", srv.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_WithRetryCanceled(t *testing.T) {
+	srv := &failingServer{errs: []error{status.Error(codes.Unavailable, "down"), status.Error(codes.Unavailable, "down")}}
+	c := newTestClient(t, srv, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Status(ctx); status.Code(err) != codes.Unavailable {
+		t.Errorf("Status() error = %v, want the last error", err)
+	}
+	if srv.calls != 1 {
+		t.Errorf("calls = %d, want 1", srv.calls)
+	}
+}
@@ -0,0 +1,101 @@
+package playerclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrNotFound           = errors.New("audio not found")
+	ErrCurrentAudio       = errors.New("audio is the current one")
+	ErrVersionMismatch    = errors.New("audio version mismatch")
+	ErrNoAudio            = errors.New("no audio to play")
+	ErrPlayerClosed       = errors.New("player closed")
+	ErrPositionOutOfRange = errors.New("position is out of the current audio")
+	ErrNotInTrash         = errors.New("audio not found in trash")
+	ErrNothingToUndo      = errors.New("nothing to undo")
+	ErrNothingToRedo      = errors.New("nothing to redo")
+	ErrHistoryDisabled    = errors.New("history is disabled")
+	ErrSleepTimerInPast   = errors.New("sleep timer time is in the past")
+	ErrQueueFull          = errors.New("player command queue is full")
+	ErrAuditDisabled      = errors.New("audit log is disabled")
+	ErrSlowSubscriber     = errors.New("subscriber does not read events fast enough")
+	ErrShuttingDown       = errors.New("server is shutting down")
+)
+
+// reasonErrors maps ErrorInfo reasons to the errors above.
+var reasonErrors = map[string]error{
+	grpcapi.ErrorReason_AUDIO_NOT_FOUND.String():        ErrNotFound,
+	grpcapi.ErrorReason_AUDIO_IS_CURRENT.String():       ErrCurrentAudio,
+	grpcapi.ErrorReason_AUDIO_VERSION_MISMATCH.String(): ErrVersionMismatch,
+	grpcapi.ErrorReason_NO_AUDIO.String():               ErrNoAudio,
+	grpcapi.ErrorReason_PLAYER_CLOSED.String():          ErrPlayerClosed,
+	grpcapi.ErrorReason_POSITION_OUT_OF_RANGE.String():  ErrPositionOutOfRange,
+	grpcapi.ErrorReason_AUDIO_NOT_IN_TRASH.String():     ErrNotInTrash,
+	grpcapi.ErrorReason_NOTHING_TO_UNDO.String():        ErrNothingToUndo,
+	grpcapi.ErrorReason_NOTHING_TO_REDO.String():        ErrNothingToRedo,
+	grpcapi.ErrorReason_HISTORY_DISABLED.String():       ErrHistoryDisabled,
+	grpcapi.ErrorReason_SLEEP_TIMER_IN_PAST.String():    ErrSleepTimerInPast,
+	grpcapi.ErrorReason_COMMAND_QUEUE_FULL.String():     ErrQueueFull,
+	grpcapi.ErrorReason_AUDIT_DISABLED.String():         ErrAuditDisabled,
+	grpcapi.ErrorReason_SLOW_SUBSCRIBER.String():        ErrSlowSubscriber,
+	grpcapi.ErrorReason_SHUTTING_DOWN.String():          ErrShuttingDown,
+	grpcapi.ErrorReason_DEADLINE_EXCEEDED.String():      context.DeadlineExceeded,
+	grpcapi.ErrorReason_CANCELED.String():               context.Canceled,
+}
+
+// Error is an error returned by the server. It matches one of the Err
+// variables with errors.Is if the server reported the reason of the error,
+// the status is available with status.FromError.
+type Error struct {
+	// Reason is the reason of the error reported by the server, e.g. AUDIO_NOT_FOUND.
+	Reason string
+	// AudioId is the id of the audio the error relates to, if any.
+	AudioId string
+
+	st  *status.Status
+	err error
+}
+
+func (e *Error) Error() string {
+	return e.st.Code().String() + ": " + e.st.Message()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Code returns the status code of the error.
+func (e *Error) Code() codes.Code {
+	return e.st.Code()
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	return e.st
+}
+
+// fromStatus converts a status error to Error, other errors are returned as is.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+	e := &Error{st: st}
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			if d.GetDomain() == grpcapi.ErrorDomain {
+				e.Reason = d.GetReason()
+				e.err = reasonErrors[e.Reason]
+			}
+		case *errdetails.ResourceInfo:
+			e.AudioId = d.GetResourceName()
+		}
+	}
+	return e
+}
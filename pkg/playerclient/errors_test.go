@@ -0,0 +1,99 @@
+package playerclient
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+func statusError(t *testing.T, code codes.Code, details ...protoadapt.MessageV1) error {
+	t.Helper()
+	st, err := status.New(code, "failed").WithDetails(details...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.Err()
+}
+
+func TestFromStatus_Reasons(t *testing.T) {
+	for value, reason := range grpcapi.ErrorReason_name {
+		if value == int32(grpcapi.ErrorReason_ERROR_REASON_UNSPECIFIED) {
+			continue
+		}
+		t.Run(reason, func(t *testing.T) {
+			want, ok := reasonErrors[reason]
+			if !ok {
+				t.Fatalf("no error for reason %s", reason)
+			}
+			err := fromStatus(statusError(t, codes.FailedPrecondition, &errdetails.ErrorInfo{Reason: reason, Domain: grpcapi.ErrorDomain}))
+			if !errors.Is(err, want) {
+				t.Errorf("fromStatus() = %v, want %v", err, want)
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Reason != reason || e.Code() != codes.FailedPrecondition {
+				t.Errorf("fromStatus() = %#v, want Error with reason %s", err, reason)
+			}
+		})
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	notFound := &errdetails.ErrorInfo{Reason: grpcapi.ErrorReason_AUDIO_NOT_FOUND.String(), Domain: grpcapi.ErrorDomain}
+	plain := errors.New("plain")
+	tests := []struct {
+		name       string
+		err        error
+		wantIs     error
+		wantReason string
+		wantAudio  string
+	}{
+		{name: "not a status", err: plain, wantIs: plain},
+		{
+			name:       "reason and audio",
+			err:        statusError(t, codes.NotFound, notFound, &errdetails.ResourceInfo{ResourceType: "audio", ResourceName: "a1"}),
+			wantIs:     ErrNotFound,
+			wantReason: "AUDIO_NOT_FOUND",
+			wantAudio:  "a1",
+		},
+		{
+			name: "other domain",
+			err: statusError(t, codes.NotFound,
+				&errdetails.ErrorInfo{Reason: grpcapi.ErrorReason_AUDIO_NOT_FOUND.String(), Domain: "other.example"}),
+		},
+		{name: "unknown reason", err: statusError(t, codes.Internal, &errdetails.ErrorInfo{Reason: "NEW", Domain: grpcapi.ErrorDomain}), wantReason: "NEW"},
+		{name: "no details", err: statusError(t, codes.Unauthenticated)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fromStatus(tt.err)
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("fromStatus() = %v, want %v", err, tt.wantIs)
+			}
+			if tt.err == plain {
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("fromStatus() = %#v, want *Error", err)
+			}
+			if tt.wantIs == nil && e.Unwrap() != nil {
+				t.Errorf("Unwrap() = %v, want nil", e.Unwrap())
+			}
+			if e.Reason != tt.wantReason || e.AudioId != tt.wantAudio {
+				t.Errorf("reason, audio = %q, %q, want %q, %q", e.Reason, e.AudioId, tt.wantReason, tt.wantAudio)
+			}
+			if got, want := status.Code(err), status.Code(tt.err); got != want {
+				t.Errorf("status code = %s, want %s", got, want)
+			}
+			if got, want := e.Error(), fmt.Sprintf("%s: failed", e.Code()); got != want {
+				t.Errorf("Error() = %q, want %q", got, want)
+			}
+		})
+	}
+}
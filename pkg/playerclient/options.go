@@ -0,0 +1,106 @@
+package playerclient
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// RetryPolicy sets how idempotent calls are retried after transient errors:
+// Unavailable and ResourceExhausted with RetryInfo.
+type RetryPolicy struct {
+	// MaxAttempts is the maximal number of attempts including the first one,
+	// values below two disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry,
+	// it doubles with every next retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+type options struct {
+	tls         *tls.Config
+	apiKey      string
+	token       string
+	retry       RetryPolicy
+	dialOptions []grpc.DialOption
+}
+
+// Option configures the client.
+type Option func(*options)
+
+// WithTLS makes the client connect with TLS, see tls.Config
+// for the client certificate of mutual TLS.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tls = cfg
+	}
+}
+
+// WithAPIKey makes the client authenticate with the API key.
+func WithAPIKey(key string) Option {
+	return func(o *options) {
+		o.apiKey = key
+	}
+}
+
+// WithToken makes the client authenticate with the JWT bearer token.
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = p
+	}
+}
+
+// WithDialOptions adds gRPC dial options, e.g. interceptors.
+// They are ignored by NewFromConn.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+// NewCallCredentials returns credentials sending the API key and the JWT bearer token
+// with every call, empty values are not sent. They are used by New and NewFromConn
+// given WithAPIKey or WithToken, and may be set on a connection made by other means.
+// If requireTLS is true, calls over a connection without TLS fail.
+func NewCallCredentials(apiKey, token string, requireTLS bool) credentials.PerRPCCredentials {
+	md := map[string]string{}
+	if apiKey != "" {
+		md["x-api-key"] = apiKey
+	}
+	if token != "" {
+		md["authorization"] = "Bearer " + token
+	}
+	return callCredentials{md: md, useTLS: requireTLS}
+}
+
+// callCredentials sends API key and bearer token with every call.
+type callCredentials struct {
+	md     map[string]string
+	useTLS bool
+}
+
+func (c callCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return c.md, nil
+}
+
+func (c callCredentials) RequireTransportSecurity() bool {
+	return c.useTLS
+}
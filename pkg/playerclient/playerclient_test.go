@@ -0,0 +1,82 @@
+package playerclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/playerclient"
+	"github.com/Karzoug/gocloudcamp/pkg/playertest"
+)
+
+// TestClient_Errors checks the errors of the client against the service.
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+	srv := playertest.New(t, playertest.WithAudios(playertest.Audio("a", time.Minute), playertest.Audio("b", time.Minute)))
+	c := srv.Client
+	audios, _, err := c.ListAudio(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Play(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.WaitEvent(t, playertest.StateChanged)
+
+	tests := []struct {
+		name      string
+		call      func() error
+		want      error
+		wantAudio string
+	}{
+		{
+			name:      "unknown audio",
+			call:      func() error { _, err := c.GetAudio(ctx, "cn0000000000000000a0"); return err },
+			want:      playerclient.ErrNotFound,
+			wantAudio: "cn0000000000000000a0",
+		},
+		{
+			name:      "version mismatch",
+			call:      func() error { return c.DeleteAudio(ctx, audios[1].Id, audios[1].Version+1) },
+			want:      playerclient.ErrVersionMismatch,
+			wantAudio: audios[1].Id,
+		},
+		{
+			name:      "not in trash",
+			call:      func() error { _, _, err := c.RestoreAudio(ctx, audios[1].Id); return err },
+			want:      playerclient.ErrNotInTrash,
+			wantAudio: audios[1].Id,
+		},
+		{
+			name: "position out of range",
+			call: func() error { return c.Seek(ctx, time.Hour) },
+			want: playerclient.ErrPositionOutOfRange,
+		},
+		{
+			name: "sleep timer in past",
+			call: func() error { _, err := c.SetSleepTimerAt(ctx, srv.Clock.Now().Add(-time.Hour)); return err },
+			want: playerclient.ErrSleepTimerInPast,
+		},
+		{
+			name: "nothing to redo",
+			call: func() error { _, err := c.Redo(ctx, true); return err },
+			want: playerclient.ErrNothingToRedo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			var e *playerclient.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("error = %#v, want *playerclient.Error", err)
+			}
+			if e.AudioId != tt.wantAudio {
+				t.Errorf("AudioId = %q, want %q", e.AudioId, tt.wantAudio)
+			}
+		})
+	}
+}
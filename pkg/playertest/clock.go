@@ -0,0 +1,98 @@
+package playertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/clock"
+)
+
+var _ clock.Clock = (*Clock)(nil)
+
+// Clock is a clock.Clock that moves only when Advance is called.
+type Clock struct {
+	mtx    sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock creates a clock showing now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *Clock) NewTimer(d time.Duration) clock.Timer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t := &timer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock by d and fires the timers due by then in order of their deadlines.
+func (c *Clock) Advance(d time.Duration) {
+	for _, t := range c.expire(d) {
+		t.ch <- t.deadline
+	}
+}
+
+// expire moves the clock by d and removes the timers due by then,
+// they are returned in order of their deadlines.
+func (c *Clock) expire(d time.Duration) []*timer {
+	c.mtx.Lock()
+	c.now = c.now.Add(d)
+	var due []*timer
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = pending
+	c.mtx.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].deadline.Before(due[j].deadline) })
+	return due
+}
+
+// timer is a timer of Clock.
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mtx.Lock()
+	defer t.clock.mtx.Unlock()
+
+	for i, pt := range t.clock.timers {
+		if pt == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package playertest
+
+import (
+	"testing"
+	"time"
+)
+
+var start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestClock_Expire(t *testing.T) {
+	tests := []struct {
+		name    string
+		timers  []time.Duration
+		advance time.Duration
+		want    []time.Duration
+		pending int
+	}{
+		{
+			name:    "in order of deadlines",
+			timers:  []time.Duration{3 * time.Second, time.Second, 2 * time.Second},
+			advance: 3 * time.Second,
+			want:    []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:    "not due timers are kept",
+			timers:  []time.Duration{5 * time.Second, time.Second, 4 * time.Second},
+			advance: 4 * time.Second,
+			want:    []time.Duration{time.Second, 4 * time.Second},
+			pending: 1,
+		},
+		{
+			name:    "none due",
+			timers:  []time.Duration{2 * time.Second},
+			advance: time.Second,
+			pending: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClock(start)
+			for _, d := range tt.timers {
+				c.NewTimer(d)
+			}
+
+			due := c.expire(tt.advance)
+
+			if len(due) != len(tt.want) {
+				t.Fatalf("expire() = %d timers, want %d", len(due), len(tt.want))
+			}
+			for i, d := range tt.want {
+				if want := start.Add(d); !due[i].deadline.Equal(want) {
+					t.Errorf("timer %d deadline = %v, want %v", i, due[i].deadline, want)
+				}
+			}
+			if len(c.timers) != tt.pending {
+				t.Errorf("pending timers = %d, want %d", len(c.timers), tt.pending)
+			}
+			if want := start.Add(tt.advance); !c.Now().Equal(want) {
+				t.Errorf("Now() = %v, want %v", c.Now(), want)
+			}
+		})
+	}
+}
+
+func TestClock_Advance(t *testing.T) {
+	c := NewClock(start)
+	fired := c.NewTimer(time.Second)
+	stopped := c.NewTimer(time.Second)
+	later := c.NewTimer(time.Minute)
+	now := c.NewTimer(0)
+
+	select {
+	case got := <-now.C():
+		if !got.Equal(start) {
+			t.Errorf("zero timer fired at %v, want %v", got, start)
+		}
+	default:
+		t.Error("zero timer is not fired at once")
+	}
+
+	if !stopped.Stop() {
+		t.Error("Stop() of a pending timer = false, want true")
+	}
+	c.Advance(2 * time.Second)
+
+	select {
+	case got := <-fired.C():
+		// the timer gets its deadline, not the time the clock is moved to
+		if want := start.Add(time.Second); !got.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", got, want)
+		}
+	default:
+		t.Error("due timer is not fired")
+	}
+	if fired.Stop() {
+		t.Error("Stop() of a fired timer = true, want false")
+	}
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer is fired")
+	case <-later.C():
+		t.Error("not due timer is fired")
+	default:
+	}
+}
@@ -0,0 +1,260 @@
+// Package playertest starts the player service in process for tests
+// of services depending on it:
+//
+//	func TestDJ(t *testing.T) {
+//		srv := playertest.New(t, playertest.WithAudios(playertest.Audio("intro", time.Minute)))
+//		if err := srv.Client.Play(ctx); err != nil {
+//			t.Fatal(err)
+//		}
+//		srv.Advance(time.Minute)
+//		srv.WaitEvent(t, playertest.StateChanged)
+//		...
+//	}
+//
+// The server runs on an in-memory listener with an in-memory playlist,
+// the playback is timed by a clock that moves only when Advance is called.
+package playertest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/internal/auth"
+	"github.com/Karzoug/gocloudcamp/internal/events"
+	"github.com/Karzoug/gocloudcamp/internal/models"
+	"github.com/Karzoug/gocloudcamp/internal/player"
+	"github.com/Karzoug/gocloudcamp/internal/playlist"
+	"github.com/Karzoug/gocloudcamp/internal/playlist/memory"
+	"github.com/Karzoug/gocloudcamp/internal/server"
+	"github.com/Karzoug/gocloudcamp/pkg/grpcapi"
+	"github.com/Karzoug/gocloudcamp/pkg/playerclient"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	bufferSize   = 1 << 20
+	eventsBuffer = 1024
+	// DefaultEventTimeout is the time WaitEvent waits for an event unless WithEventTimeout is given.
+	DefaultEventTimeout = 5 * time.Second
+	// trashRetention is the time deleted audios are kept in the trash.
+	trashRetention = time.Hour
+	historyDepth   = 100
+)
+
+// EventType is a type of player event.
+type EventType string
+
+const (
+	StateChanged  EventType = "state_changed"
+	TrackChanged  EventType = "track_changed"
+	AudioCreated  EventType = "audio_created"
+	AudioUpdated  EventType = "audio_updated"
+	AudioDeleted  EventType = "audio_deleted"
+	AudioMoved    EventType = "audio_moved"
+	AudioRestored EventType = "audio_restored"
+)
+
+// Event is a change of the player or the playlist.
+type Event struct {
+	Id   uint64
+	Type EventType
+	// State is the new state of the player for StateChanged.
+	State playerclient.State
+	// Audio is the loaded audio for TrackChanged, the created, updated
+	// or restored one for AudioCreated, AudioUpdated and AudioRestored.
+	Audio *playerclient.Audio
+	// AudioId is the id of the deleted or moved audio.
+	AudioId string
+	// Index is the requested position of the moved audio
+	// or the position of the restored one.
+	Index int
+}
+
+// Audio returns an audio to be added to the playlist with WithAudios.
+func Audio(name string, duration time.Duration) playerclient.Audio {
+	return playerclient.Audio{Name: name, Duration: duration}
+}
+
+type options struct {
+	audios       []playerclient.Audio
+	logger       *slog.Logger
+	start        time.Time
+	eventTimeout time.Duration
+}
+
+// Option configures the server.
+type Option func(*options)
+
+// WithAudios adds the audios to the playlist before the server starts,
+// their ids are assigned by the playlist.
+func WithAudios(audios ...playerclient.Audio) Option {
+	return func(o *options) {
+		o.audios = append(o.audios, audios...)
+	}
+}
+
+// WithLogger makes the server log to the logger, logs are discarded by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithStartTime sets the time the clock shows at start.
+func WithStartTime(t time.Time) Option {
+	return func(o *options) {
+		o.start = t
+	}
+}
+
+// WithEventTimeout replaces DefaultEventTimeout.
+func WithEventTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.eventTimeout = d
+	}
+}
+
+// Server is the player service running in process.
+type Server struct {
+	// Client is connected to the server, its calls are not retried.
+	Client *playerclient.Client
+	// Conn is the connection of Client.
+	Conn  *grpc.ClientConn
+	Clock *Clock
+
+	sub          *events.Subscription
+	eventTimeout time.Duration
+}
+
+// New starts the server, it is stopped when the test finishes.
+func New(tb testing.TB, opts ...Option) *Server {
+	tb.Helper()
+
+	o := options{
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		start:        time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		eventTimeout: DefaultEventTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	clock := NewClock(o.start)
+	pl := memory.New(o.logger, memory.WithTrashRetention(trashRetention), memory.WithClock(clock))
+	for _, a := range o.audios {
+		if _, err := pl.Add(context.Background(), models.Audio{Name: a.Name, Duration: a.Duration}); err != nil {
+			tb.Fatalf("add audio error: %v", err)
+		}
+	}
+
+	bus := events.NewBus(eventsBuffer)
+	// the subscription is made before the player starts to receive all its events
+	sub, _, _ := bus.Subscribe(nil, 0)
+	// audios added above are not in the history, so they can not be undone
+	h := playlist.NewHistory(pl, historyDepth, auth.Subject)
+	p := player.New(h, o.logger, player.WithClock(clock), player.WithEvents(bus))
+
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(server.UnaryValidationInterceptor(), server.UnaryErrorInterceptor()),
+		grpc.ChainStreamInterceptor(server.StreamValidationInterceptor(), server.StreamErrorInterceptor()),
+	)
+	grpcapi.RegisterPlayerServiceServer(s, server.New(p, o.logger, server.WithEvents(bus), server.WithHistory(h)))
+
+	lis := bufconn.Listen(bufferSize)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		tb.Fatalf("dial error: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+		sub.Close()
+		p.Close()
+	})
+
+	return &Server{
+		Client:       playerclient.NewFromConn(conn, playerclient.WithRetryPolicy(playerclient.RetryPolicy{MaxAttempts: 1})),
+		Conn:         conn,
+		Clock:        clock,
+		sub:          sub,
+		eventTimeout: o.eventTimeout,
+	}
+}
+
+// Advance moves the clock of the playback by d. Audios ending by then
+// end as soon as the player handles it, use WaitEvent to wait for it.
+func (s *Server) Advance(d time.Duration) {
+	s.Clock.Advance(d)
+}
+
+// NextEvent returns the next event, the test fails if there is none in time.
+func (s *Server) NextEvent(tb testing.TB) Event {
+	tb.Helper()
+
+	select {
+	case e, ok := <-s.sub.Events():
+		if !ok {
+			tb.Fatal("events are not read fast enough")
+		}
+		return fromEvent(e)
+	case <-time.After(s.eventTimeout):
+		tb.Fatalf("no event within %s", s.eventTimeout)
+	}
+	return Event{}
+}
+
+// WaitEvent skips events until one of the type, the test fails if there is none in time.
+func (s *Server) WaitEvent(tb testing.TB, typ EventType) Event {
+	tb.Helper()
+
+	deadline := time.After(s.eventTimeout)
+	for {
+		select {
+		case e, ok := <-s.sub.Events():
+			if !ok {
+				tb.Fatal("events are not read fast enough")
+			}
+			if EventType(e.Type.String()) == typ {
+				return fromEvent(e)
+			}
+		case <-deadline:
+			tb.Fatalf("no %s event within %s", typ, s.eventTimeout)
+			return Event{}
+		}
+	}
+}
+
+func fromEvent(e events.Event) Event {
+	res := Event{
+		Id:      e.Id,
+		Type:    EventType(e.Type.String()),
+		State:   playerclient.State(e.State),
+		AudioId: e.AudioId,
+		Index:   e.Index,
+	}
+	if e.Audio != nil {
+		res.Audio = &playerclient.Audio{
+			Id:       e.Audio.Id,
+			Name:     e.Audio.Name,
+			Duration: e.Audio.Duration,
+			Version:  e.Audio.Version,
+		}
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package playertest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Karzoug/gocloudcamp/pkg/playerclient"
+)
+
+// fatalTB records the failure of a test instead of failing it,
+// it must be used in its own goroutine as Fatal stops the goroutine.
+type fatalTB struct {
+	testing.TB
+	failure string
+}
+
+func (tb *fatalTB) Fatal(args ...any) {
+	tb.failure = fmt.Sprint(args...)
+	runtime.Goexit()
+}
+
+func (tb *fatalTB) Fatalf(format string, args ...any) {
+	tb.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// run calls f with tb and returns the failure, empty if f has not failed.
+func (tb *fatalTB) run(f func(testing.TB)) string {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(tb)
+	}()
+	<-done
+	return tb.failure
+}
+
+func TestServer_WaitEvent(t *testing.T) {
+	srv := New(t, WithAudios(Audio("a", time.Minute)))
+	if err := srv.Client.Play(context.Background()); err != nil {
+		t.Fatalf("Play() error: %v", err)
+	}
+
+	// TrackChanged of the loaded audio is skipped
+	e := srv.WaitEvent(t, StateChanged)
+	if e.Type != StateChanged || e.State != playerclient.Playing {
+		t.Errorf("WaitEvent() = %+v, want %s to %s", e, StateChanged, playerclient.Playing)
+	}
+
+	srv.Advance(time.Minute)
+	e = srv.WaitEvent(t, StateChanged)
+	if e.State != playerclient.NoActiveAudio {
+		t.Errorf("WaitEvent() state after the audio end = %s, want %s", e.State, playerclient.NoActiveAudio)
+	}
+}
+
+func TestServer_WaitEventTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	srv := New(t, WithAudios(Audio("a", time.Minute)), WithEventTimeout(timeout))
+	if err := srv.Client.Play(context.Background()); err != nil {
+		t.Fatalf("Play() error: %v", err)
+	}
+
+	tb := &fatalTB{TB: t}
+	begin := time.Now()
+	failure := tb.run(func(tb testing.TB) {
+		srv.WaitEvent(tb, AudioDeleted)
+	})
+
+	if want := fmt.Sprintf("no %s event within %s", AudioDeleted, timeout); failure != want {
+		t.Errorf("WaitEvent() failure = %q, want %q", failure, want)
+	}
+	if elapsed := time.Since(begin); elapsed < timeout {
+		t.Errorf("WaitEvent() failed after %s, want at least %s", elapsed, timeout)
+	}
+}
+
+func TestServer_NextEventTimeout(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	srv := New(t, WithEventTimeout(timeout))
+
+	tb := &fatalTB{TB: t}
+	failure := tb.run(func(tb testing.TB) {
+		srv.NextEvent(tb)
+	})
+
+	if want := fmt.Sprintf("no event within %s", timeout); failure != want {
+		t.Errorf("NextEvent() failure = %q, want %q", failure, want)
+	}
+}